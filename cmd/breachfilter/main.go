@@ -0,0 +1,126 @@
+package main
+
+import (
+	"AuthService/internal/lib/breach"
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// breachfilter builds the bloom filter the auth service checks new passwords against.
+// The input is either a file of "SHA1:COUNT" lines, such as the HIBP ordered-by-hash
+// download, or a directory of range files named after their 5 character prefix
+func main() {
+	var input, output string
+	var falsePositiveRate float64
+	var minCount int
+
+	flag.StringVar(&input, "input", "", "file of SHA1:COUNT lines or directory of range files")
+	flag.StringVar(&output, "output", "", "path of the bloom filter to write")
+	flag.Float64Var(&falsePositiveRate, "fp-rate", 0.001, "share of unbreached passwords reported as breached")
+	flag.IntVar(&minCount, "min-count", 1, "skip hashes seen fewer times than this")
+	flag.Parse()
+
+	if input == "" || output == "" {
+		log.Fatal("input and output are required")
+	}
+
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		log.Fatal("fp-rate must be between 0 and 1")
+	}
+
+	// The filter is sized up front, so the corpus is read twice
+	var n uint64
+	if err := walk(input, minCount, func(string) error { n++; return nil }); err != nil {
+		log.Fatal(err)
+	}
+
+	filter := breach.NewBloom(n, falsePositiveRate)
+	if err := walk(input, minCount, filter.Add); err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := bufio.NewWriter(file)
+	if _, err := filter.WriteTo(w); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := file.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("wrote %d hashes to %s", n, output)
+}
+
+// walk calls fn with every full hash of the corpus seen at least minCount times
+func walk(input string, minCount int, fn func(hash string) error) error {
+	info, err := os.Stat(input)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return readFile(input, "", minCount, fn)
+	}
+
+	entries, err := os.ReadDir(input)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		prefix := strings.TrimSuffix(entry.Name(), ".txt")
+		if entry.IsDir() || len(prefix) != 5 {
+			continue
+		}
+
+		if err := readFile(filepath.Join(input, entry.Name()), strings.ToUpper(prefix), minCount, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readFile(path, prefix string, minCount int, fn func(hash string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		hash, count, err := breach.ParseLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		if count < minCount {
+			continue
+		}
+
+		if err := fn(prefix + hash); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -8,7 +8,6 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 )
 
@@ -30,10 +29,14 @@ func main() {
 
 	log.Info("Starting account service", "env", cfg.Env)
 
-	application := app.New(log, strconv.Itoa(cfg.GRPC.AuthPort), cfg.Storage, cfg.TokenTTL)
+	application := app.New(log, cfg)
 
 	go application.GRPCSrv.MustRun()
 
+	for _, w := range application.Workers {
+		go w.Run()
+	}
+
 	// Graceful shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
@@ -42,6 +45,10 @@ func main() {
 
 	log.Info("Application stopped", slog.String("signal", sign.String()))
 
+	for _, w := range application.Workers {
+		w.Stop()
+	}
+
 	application.GRPCSrv.Stop()
 }
 
@@ -2,18 +2,40 @@ package app
 
 import (
 	grpcapp "AuthService/internal/app/grpc"
+	"AuthService/internal/app/worker"
+	"AuthService/internal/config"
+	authgrpc "AuthService/internal/grpc/auth"
+	interceptorsgrpc "AuthService/internal/grpc/interceptors"
+	"AuthService/internal/http/api"
+	"AuthService/internal/http/middleware"
+	"AuthService/internal/lib/abuse"
+	"AuthService/internal/lib/breach"
+	"AuthService/internal/lib/clientip"
+	"AuthService/internal/lib/mailer"
+	"AuthService/internal/lib/password"
+	"AuthService/internal/lib/pow"
+	"AuthService/internal/lib/ratelimit"
+	"AuthService/internal/lib/risk"
+	"AuthService/internal/services/account"
 	"AuthService/internal/services/auth"
 	"AuthService/internal/storage/postgres"
+	"context"
+	"crypto/rand"
+	"github.com/go-redis/redis/v8"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
 	"log/slog"
+	"strconv"
 	"time"
 )
 
 type App struct {
 	GRPCSrv *grpcapp.App
+	Workers []*worker.Worker
 }
 
-func New(log *slog.Logger, grpcPort, storagePath string, tokenTTL time.Duration) *App {
-	storage, err := postgres.NewPostgres(storagePath)
+func New(log *slog.Logger, cfg *config.Config) *App {
+	storage, err := postgres.NewPostgres(cfg.Storage)
 	if err != nil {
 		panic(err)
 	}
@@ -23,11 +45,259 @@ func New(log *slog.Logger, grpcPort, storagePath string, tokenTTL time.Duration)
 		panic(err)
 	}
 
-	AuthService := auth.New(log, storage, tokenTTL)
+	var mail mailer.Mailer
+	switch cfg.Mail.Driver {
+	case "smtp":
+		mail = mailer.NewSMTP(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From)
+	default:
+		mail = mailer.NewLog(log)
+	}
+
+	argon2id := password.Argon2id{
+		Memory:      cfg.PasswordHashing.Argon2id.Memory,
+		Iterations:  cfg.PasswordHashing.Argon2id.Iterations,
+		Parallelism: cfg.PasswordHashing.Argon2id.Parallelism,
+		SaltLength:  cfg.PasswordHashing.Argon2id.SaltLength,
+		KeyLength:   cfg.PasswordHashing.Argon2id.KeyLength,
+	}
+	bcrypt := password.Bcrypt{Cost: cfg.PasswordHashing.BcryptCost}
+
+	// New passwords are hashed with the configured algorithm, the other one is still verified
+	hasher := password.NewHasher(argon2id, bcrypt)
+	if cfg.PasswordHashing.Algorithm == "bcrypt" {
+		hasher = password.NewHasher(bcrypt, argon2id)
+	}
+
+	if cfg.PasswordHashing.Pepper.File != "" {
+		pepper, err := password.LoadPepper(cfg.PasswordHashing.Pepper.File, cfg.PasswordHashing.Pepper.CurrentVersion)
+		if err != nil {
+			panic(err)
+		}
+
+		// Hashes of an older pepper version are re-peppered as their users log in
+		hasher.WithPepper(pepper)
+	}
+
+	var breachChecker breach.Checker
+	if cfg.BreachedPasswords.Enabled {
+		switch cfg.BreachedPasswords.Source {
+		case "range":
+			breachChecker, err = breach.NewRangeDir(cfg.BreachedPasswords.Path, cfg.BreachedPasswords.MinCount)
+		default:
+			breachChecker, err = breach.LoadBloom(cfg.BreachedPasswords.Path)
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// The postgres storage implements every repository of the auth service
+	AuthService := auth.New(
+		log,
+		auth.Repositories{
+			Users:          storage,
+			Devices:        storage,
+			LoginEvents:    storage,
+			Sessions:       storage,
+			PasswordResets: storage,
+			EmailChanges:   storage,
+			Audit:          storage,
+			DataExports:    storage,
+			Invitations:    storage,
+			Policies:       storage,
+			Lockouts:       storage,
+			Challenges:     storage,
+		},
+		mail,
+		hasher,
+		breachChecker,
+		cfg.TokenTTL,
+		auth.Settings{
+			DeviceTTL: cfg.TrustedDevice.TTL,
+			RiskPolicy: risk.Policy{
+				Enabled:            cfg.Risk.Enabled,
+				NewDeviceWeight:    cfg.Risk.NewDeviceWeight,
+				NewIPWeight:        cfg.Risk.NewIPWeight,
+				NewNetworkWeight:   cfg.Risk.NewNetworkWeight,
+				FailureWeight:      cfg.Risk.FailureWeight,
+				MaxFailureWeight:   cfg.Risk.MaxFailureWeight,
+				DormantWeight:      cfg.Risk.DormantWeight,
+				DormantAfter:       cfg.Risk.DormantAfter,
+				ChallengeThreshold: cfg.Risk.ChallengeThreshold,
+				BlockThreshold:     cfg.Risk.BlockThreshold,
+			},
+			AbusePolicy: abuse.Policy{
+				Enabled:           cfg.LoginAbuse.Enabled,
+				Window:            cfg.LoginAbuse.Window,
+				IPFailures:        abuseRule(cfg.LoginAbuse.IPFailures),
+				NetworkFailures:   abuseRule(cfg.LoginAbuse.NetworkFailures),
+				DistinctUsernames: abuseRule(cfg.LoginAbuse.DistinctUsernames),
+				SlowDownDelay:     cfg.LoginAbuse.SlowDownDelay,
+			},
+			ProofOfWork: pow.Policy{
+				Enabled:   cfg.ProofOfWork.Enabled,
+				Key:       powKey(cfg.ProofOfWork.Secret),
+				TTL:       cfg.ProofOfWork.TTL,
+				Threshold: cfg.ProofOfWork.Threshold,
+				Base:      cfg.ProofOfWork.BaseDifficulty,
+				Step:      cfg.ProofOfWork.Step,
+				Max:       cfg.ProofOfWork.MaxDifficulty,
+			},
+			PasswordPolicy: password.Policy{
+				MinLength:           cfg.PasswordPolicy.MinLength,
+				MaxLength:           cfg.PasswordPolicy.MaxLength,
+				RequireLowercase:    cfg.PasswordPolicy.RequireLowercase,
+				RequireUppercase:    cfg.PasswordPolicy.RequireUppercase,
+				RequireDigit:        cfg.PasswordPolicy.RequireDigit,
+				RequireSymbol:       cfg.PasswordPolicy.RequireSymbol,
+				MinCharacterClasses: cfg.PasswordPolicy.MinCharacterClasses,
+				ForbidUserInfo:      cfg.PasswordPolicy.ForbidUserInfo,
+				MinStrength:         cfg.PasswordPolicy.MinStrength,
+			},
+			PasswordHistory:   cfg.PasswordPolicy.HistorySize,
+			PasswordMaxAge:    cfg.PasswordPolicy.MaxAge,
+			PasswordWarning:   cfg.PasswordPolicy.ExpiryWarning,
+			RiskFailureWindow: cfg.Risk.FailureWindow,
+			StepUpMaxAge:      cfg.StepUp.MaxAge,
+			StepUpACR:         cfg.StepUp.RequiredACR,
+
+			RequireVerifiedEmail: cfg.Verification.Required,
+			VerificationTTL:      cfg.Verification.TTL,
+			VerificationURL:      cfg.Verification.LinkURL,
+
+			RefreshTokenTTL: cfg.RefreshTTL,
+			ResetTTL:        cfg.PasswordReset.TTL,
+			ResetURL:        cfg.PasswordReset.LinkURL,
+			SetPasswordTTL:  cfg.PasswordReset.SetPasswordTTL,
+
+			EmailChangeTTL:       cfg.EmailChange.TTL,
+			EmailChangeRevertTTL: cfg.EmailChange.RevertTTL,
+			EmailChangeURL:       cfg.EmailChange.ConfirmURL,
+			EmailRevertURL:       cfg.EmailChange.RevertURL,
+
+			DeletionGracePeriod: cfg.AccountDeletion.GracePeriod,
+			DeletionAnonymize:   cfg.AccountDeletion.Anonymize,
+
+			ExportTTL:   cfg.DataExport.TTL,
+			ExportLease: cfg.DataExport.Lease,
+
+			UsernameReservation:    cfg.UsernameChange.Reservation,
+			UsernameChangeInterval: cfg.UsernameChange.MinInterval,
+
+			RegistrationMode: cfg.Registration.Mode,
+			InvitationTTL:    cfg.Registration.InvitationTTL,
+			InvitationURL:    cfg.Registration.InvitationURL,
+
+			LockoutThreshold:   cfg.LoginLockout.Threshold,
+			LockoutBaseDelay:   cfg.LoginLockout.BaseDelay,
+			LockoutMaxDelay:    cfg.LoginLockout.MaxDelay,
+			LockoutDuration:    cfg.LoginLockout.Duration,
+			LockoutMaxDuration: cfg.LoginLockout.MaxDuration,
+			LockoutResetAfter:  cfg.LoginLockout.ResetAfter,
+
+			AntiEnumeration: cfg.AntiEnumeration.Enabled,
+		},
+	)
+
+	AccountService := account.New(log, AuthService, storage)
+
+	proxies, err := clientip.ParseProxies(cfg.GRPC.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+
+	var interceptors []grpc.UnaryServerInterceptor
+	var middlewares []runtime.Middleware
+	if cfg.RateLimit.Enabled {
+		// gRPC calls and plain HTTP handlers take from the same IP and subject buckets
+		limiter := newRateLimiter(cfg.RateLimit)
 
-	grpcApp := grpcapp.New(log, AuthService, grpcPort)
+		interceptors = append(interceptors, interceptorsgrpc.RateLimit(log, limiter, ratelimit.Limits{
+			IP:      rateLimitRule(cfg.RateLimit.IP),
+			Subject: rateLimitRule(cfg.RateLimit.Subject),
+			Methods: rateLimitRules(cfg.RateLimit.Methods),
+		}, proxies, authgrpc.Subject))
+
+		middlewares = append(middlewares, middleware.RateLimit(log, limiter, ratelimit.Limits{
+			IP:      rateLimitRule(cfg.RateLimit.IP),
+			Subject: rateLimitRule(cfg.RateLimit.Subject),
+			Methods: rateLimitRules(cfg.RateLimit.Routes),
+		}, proxies, api.Subject))
+	}
+
+	grpcApp := grpcapp.New(
+		log,
+		AuthService,
+		AccountService,
+		proxies,
+		strconv.Itoa(cfg.GRPC.AuthPort),
+		strconv.Itoa(cfg.GRPC.AccountPort),
+		middlewares,
+		interceptors...,
+	)
 
 	return &App{
 		GRPCSrv: grpcApp,
+		Workers: []*worker.Worker{
+			worker.New(log, "account-purge", cfg.AccountDeletion.PurgeInterval, AuthService.PurgeDeletedAccounts),
+			worker.New(log, "data-export", cfg.DataExport.ProcessInterval, AuthService.ProcessDataExports),
+			worker.New(log, "login-failures-prune", cfg.LoginAbuse.PruneInterval, AuthService.PruneLoginFailures),
+			worker.New(log, "pow-prune", cfg.ProofOfWork.PruneInterval, AuthService.PruneChallenges),
+			worker.New(log, "unknown-logins-prune", cfg.LoginLockout.PruneInterval, AuthService.PruneUnknownLogins),
+		},
+	}
+}
+
+// newRateLimiter returns the limiter of the configured backend, the redis one panics
+// when redis cannot be reached at startup
+func newRateLimiter(cfg config.RateLimitConfig) ratelimit.Limiter {
+	if cfg.Backend != "redis" {
+		return ratelimit.NewMemory()
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		panic("failed to connect to redis: " + err.Error())
+	}
+
+	return ratelimit.NewRedis(client, "ratelimit:")
+}
+
+func rateLimitRule(rule config.RateLimitRule) ratelimit.Rule {
+	return ratelimit.Rule{Limit: rule.Limit, Period: rule.Period, Burst: rule.Burst}
+}
+
+func rateLimitRules(rules map[string]config.RateLimitRule) map[string]ratelimit.Rule {
+	result := make(map[string]ratelimit.Rule, len(rules))
+	for method, rule := range rules {
+		result[method] = rateLimitRule(rule)
 	}
+
+	return result
+}
+
+func abuseRule(rule config.LoginAbuseRule) abuse.Rule {
+	return abuse.Rule{Threshold: rule.Threshold, Response: abuse.Response(rule.Response)}
+}
+
+// powKey returns the key challenges are signed with, a random one when no secret is configured
+func powKey(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+
+	return key
 }
@@ -1,12 +1,18 @@
 package grpcapp
 
 import (
+	accountgrpc "AuthService/internal/grpc/account"
 	authgrpc "AuthService/internal/grpc/auth"
+	accounthttp "AuthService/internal/http/account"
+	authhttp "AuthService/internal/http/auth"
+	"AuthService/internal/lib/clientip"
 	"context"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	ssov1 "github.com/ryzhy1/protos/gen/go/sso"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	"log/slog"
 	"net"
@@ -15,22 +21,57 @@ import (
 )
 
 type App struct {
-	log           *slog.Logger
-	authServer    *grpc.Server
-	accountServer *grpc.Server
-	authPort      string
-	accountPort   string
+	log            *slog.Logger
+	authService    Auth
+	accountService Account
+	proxies        clientip.Proxies
+	middlewares    []runtime.Middleware
+	authServer     *grpc.Server
+	accountServer  *grpc.Server
+	authPort       string
+	accountPort    string
 }
 
-func New(log *slog.Logger, authService authgrpc.Auth, authPort string) *App {
-	authServer := grpc.NewServer()
-	authgrpc.Register(authServer, authService)
+// Auth is the auth service as served over gRPC and the HTTP gateway
+type Auth interface {
+	authgrpc.Auth
+	authhttp.Auth
+}
+
+// Account is the account service as served over gRPC and the HTTP gateway
+type Account interface {
+	accountgrpc.Account
+	accounthttp.Account
+}
+
+func New(
+	log *slog.Logger,
+	authService Auth,
+	accountService Account,
+	proxies clientip.Proxies,
+	authPort, accountPort string,
+	middlewares []runtime.Middleware,
+	interceptors ...grpc.UnaryServerInterceptor,
+) *App {
+	authServer := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	authgrpc.Register(authServer, authService, proxies)
 	reflection.Register(authServer)
 
+	accountServer := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	accountgrpc.Register(accountServer, accountService)
+	healthgrpc.RegisterHealthServer(accountServer, health.NewServer())
+	reflection.Register(accountServer)
+
 	return &App{
-		log:        log,
-		authServer: authServer,
-		authPort:   ":" + authPort,
+		log:            log,
+		authService:    authService,
+		accountService: accountService,
+		proxies:        proxies,
+		middlewares:    middlewares,
+		authServer:     authServer,
+		accountServer:  accountServer,
+		authPort:       ":" + authPort,
+		accountPort:    ":" + accountPort,
 	}
 }
 
@@ -65,8 +106,21 @@ func (a *App) Run() error {
 
 	go func() {
 		defer wg.Done()
+		l, err := net.Listen("tcp", a.accountPort)
+		if err != nil {
+			log.Error("failed to listen for account server", "error", err)
+			return
+		}
+		log.Info("Account gRPC server started", "port", a.accountPort)
+		if err := a.accountServer.Serve(l); err != nil {
+			log.Error("failed to serve account server", "error", err)
+		}
+	}()
 
-		mux := runtime.NewServeMux()
+	go func() {
+		defer wg.Done()
+
+		mux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(authgrpc.HeaderMatcher))
 
 		opts := []grpc.DialOption{
 			grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -78,9 +132,30 @@ func (a *App) Run() error {
 			return
 		}
 
+		// Links sent by email are opened in a browser and served by plain handlers, so are
+		// the calls the protos have no methods for. They call the services directly, past the
+		// gRPC interceptors, and get the middlewares on a mux of their own. Requests none of
+		// them serves go on to the gateway
+		handlers := runtime.NewServeMux(
+			runtime.WithMiddlewares(a.middlewares...),
+			runtime.WithRoutingErrorHandler(func(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, _ int) {
+				mux.ServeHTTP(w, r)
+			}),
+		)
+
+		if err := authhttp.Register(handlers, a.authService, a.proxies); err != nil {
+			log.Error("failed to register auth handlers", "error", err)
+			return
+		}
+
+		if err := accounthttp.Register(handlers, a.accountService); err != nil {
+			log.Error("failed to register account handlers", "error", err)
+			return
+		}
+
 		log.Info("Http server listening at", "port", ":8081")
 
-		handler := allowCORS(mux) // Добавлено CORS middleware
+		handler := allowCORS(handlers) // Добавлено CORS middleware
 		if err := http.ListenAndServe(`localhost:8081`, handler); err != nil {
 			log.Error("failed to serve grpc auth server", "error", err)
 			return
@@ -105,7 +180,7 @@ func allowCORS(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, PATCH, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Device-Fingerprint, X-Device-Token, X-Remember-Device, X-Invitation-Code, X-Pow-Solution")
 		if r.Method == "OPTIONS" {
 			return
 		}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Job is a unit of background work that is run periodically
+type Job func(ctx context.Context) error
+
+// Worker runs a job on a fixed interval until it is stopped
+type Worker struct {
+	log      *slog.Logger
+	name     string
+	interval time.Duration
+	job      Job
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func New(log *slog.Logger, name string, interval time.Duration, job Job) *Worker {
+	return &Worker{
+		log:      log,
+		name:     name,
+		interval: interval,
+		job:      job,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (w *Worker) Run() {
+	const op = "worker.Run"
+
+	log := w.log.With(
+		slog.String("op", op),
+		slog.String("worker", w.name),
+	)
+
+	defer close(w.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-w.stop
+		cancel()
+	}()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	log.Info("worker started", "interval", w.interval)
+
+	for {
+		if err := w.job(ctx); err != nil && ctx.Err() == nil {
+			log.Error("job failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop cancels the running job and waits for the worker to return
+func (w *Worker) Stop() {
+	close(w.stop)
+	<-w.done
+}
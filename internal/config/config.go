@@ -1,6 +1,8 @@
 package config
 
 import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/abuse"
 	"flag"
 	"github.com/ilyakaznacheev/cleanenv"
 	"os"
@@ -8,15 +10,238 @@ import (
 )
 
 type Config struct {
-	Env      string        `yaml:"env" env-default:"local"`
-	Storage  string        `yaml:"storage_path" env-required:"true"`
-	TokenTTL time.Duration `yaml:"token_ttl" env-required:"true"`
-	GRPC     GRPCConfig    `yaml:"grpc"`
+	Env               string                  `yaml:"env" env-default:"local"`
+	Storage           string                  `yaml:"storage_path" env-required:"true"`
+	TokenTTL          time.Duration           `yaml:"token_ttl" env-required:"true"`
+	RefreshTTL        time.Duration           `yaml:"refresh_token_ttl" env-default:"720h"`
+	GRPC              GRPCConfig              `yaml:"grpc"`
+	TrustedDevice     TrustedDeviceConfig     `yaml:"trusted_device"`
+	Risk              RiskConfig              `yaml:"risk"`
+	StepUp            StepUpConfig            `yaml:"step_up"`
+	Mail              MailConfig              `yaml:"mail"`
+	Verification      VerificationConfig      `yaml:"email_verification"`
+	PasswordReset     PasswordResetConfig     `yaml:"password_reset"`
+	EmailChange       EmailChangeConfig       `yaml:"email_change"`
+	AccountDeletion   AccountDeletionConfig   `yaml:"account_deletion"`
+	DataExport        DataExportConfig        `yaml:"data_export"`
+	UsernameChange    UsernameChangeConfig    `yaml:"username_change"`
+	Registration      RegistrationConfig      `yaml:"registration"`
+	PasswordHashing   PasswordHashingConfig   `yaml:"password_hashing"`
+	PasswordPolicy    PasswordPolicyConfig    `yaml:"password_policy"`
+	BreachedPasswords BreachedPasswordsConfig `yaml:"breached_passwords"`
+	LoginLockout      LoginLockoutConfig      `yaml:"login_lockout"`
+	RateLimit         RateLimitConfig         `yaml:"rate_limit"`
+	LoginAbuse        LoginAbuseConfig        `yaml:"login_abuse"`
+	ProofOfWork       ProofOfWorkConfig       `yaml:"proof_of_work"`
+	AntiEnumeration   AntiEnumerationConfig   `yaml:"anti_enumeration"`
 }
 
+// GRPCConfig also lists the proxies, in CIDR notation, whose x-forwarded-for metadata is
+// believed. The HTTP gateway calls the auth server from the loopback address
 type GRPCConfig struct {
-	AuthPort int `yaml:"authPort" env-required:"true"`
-	Timeout  string
+	AuthPort       int `yaml:"authPort" env-required:"true"`
+	AccountPort    int `yaml:"accountPort" env-default:"50053"`
+	Timeout        string
+	TrustedProxies []string `yaml:"trusted_proxies" env-default:"127.0.0.1/32,::1/128"`
+}
+
+type TrustedDeviceConfig struct {
+	TTL time.Duration `yaml:"ttl" env-default:"720h"`
+}
+
+type RiskConfig struct {
+	Enabled            bool          `yaml:"enabled" env-default:"true"`
+	NewDeviceWeight    int           `yaml:"new_device_weight" env-default:"20"`
+	NewIPWeight        int           `yaml:"new_ip_weight" env-default:"15"`
+	NewNetworkWeight   int           `yaml:"new_network_weight" env-default:"15"`
+	FailureWeight      int           `yaml:"failure_weight" env-default:"10"`
+	MaxFailureWeight   int           `yaml:"max_failure_weight" env-default:"40"`
+	FailureWindow      time.Duration `yaml:"failure_window" env-default:"24h"`
+	DormantWeight      int           `yaml:"dormant_weight" env-default:"20"`
+	DormantAfter       time.Duration `yaml:"dormant_after" env-default:"2160h"`
+	ChallengeThreshold int           `yaml:"challenge_threshold" env-default:"60"`
+	BlockThreshold     int           `yaml:"block_threshold" env-default:"90"`
+}
+
+type StepUpConfig struct {
+	MaxAge      time.Duration `yaml:"max_age" env-default:"10m"`
+	RequiredACR string        `yaml:"required_acr" env-default:"aal1"`
+}
+
+type MailConfig struct {
+	Driver   string `yaml:"driver" env-default:"log"`
+	From     string `yaml:"from" env-default:"no-reply@localhost"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+}
+
+type VerificationConfig struct {
+	Required bool          `yaml:"required" env-default:"false"`
+	TTL      time.Duration `yaml:"ttl" env-default:"24h"`
+	LinkURL  string        `yaml:"link_url" env-default:"http://localhost:8081/verify-email"`
+}
+
+type PasswordResetConfig struct {
+	TTL            time.Duration `yaml:"ttl" env-default:"30m"`
+	LinkURL        string        `yaml:"link_url" env-default:"http://localhost:3000/reset-password"`
+	SetPasswordTTL time.Duration `yaml:"set_password_ttl" env-default:"72h"`
+}
+
+type EmailChangeConfig struct {
+	TTL        time.Duration `yaml:"ttl" env-default:"24h"`
+	RevertTTL  time.Duration `yaml:"revert_ttl" env-default:"168h"`
+	ConfirmURL string        `yaml:"confirm_url" env-default:"http://localhost:8081/confirm-email-change"`
+	RevertURL  string        `yaml:"revert_url" env-default:"http://localhost:8081/revert-email-change"`
+}
+
+type AccountDeletionConfig struct {
+	GracePeriod   time.Duration `yaml:"grace_period" env-default:"720h"`
+	Anonymize     bool          `yaml:"anonymize" env-default:"false"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+}
+
+type DataExportConfig struct {
+	TTL             time.Duration `yaml:"ttl" env-default:"168h"`
+	ProcessInterval time.Duration `yaml:"process_interval" env-default:"10s"`
+	Lease           time.Duration `yaml:"lease" env-default:"15m"`
+}
+
+type UsernameChangeConfig struct {
+	Reservation time.Duration `yaml:"reservation" env-default:"2160h"`
+	MinInterval time.Duration `yaml:"min_interval" env-default:"720h"`
+}
+
+type RegistrationConfig struct {
+	Mode          string        `yaml:"mode" env-default:"open"`
+	InvitationTTL time.Duration `yaml:"invitation_ttl" env-default:"168h"`
+	InvitationURL string        `yaml:"invitation_url" env-default:"http://localhost:3000/register"`
+}
+
+type PasswordHashingConfig struct {
+	Algorithm  string       `yaml:"algorithm" env-default:"argon2id"`
+	Argon2id   Argon2Config `yaml:"argon2id"`
+	BcryptCost int          `yaml:"bcrypt_cost" env-default:"10"`
+	Pepper     PepperConfig `yaml:"pepper"`
+}
+
+// PepperConfig points at the secret file of pepper versions. Without a file passwords are not peppered,
+// without a current version only hashes peppered before are verified
+type PepperConfig struct {
+	File           string `yaml:"file" env:"PASSWORD_PEPPER_FILE"`
+	CurrentVersion string `yaml:"current_version" env:"PASSWORD_PEPPER_VERSION"`
+}
+
+type Argon2Config struct {
+	Memory      uint32 `yaml:"memory" env-default:"65536"`
+	Iterations  uint32 `yaml:"iterations" env-default:"3"`
+	Parallelism uint8  `yaml:"parallelism" env-default:"2"`
+	SaltLength  uint32 `yaml:"salt_length" env-default:"16"`
+	KeyLength   uint32 `yaml:"key_length" env-default:"32"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength           int  `yaml:"min_length" env-default:"8"`
+	MaxLength           int  `yaml:"max_length" env-default:"128"`
+	RequireLowercase    bool `yaml:"require_lowercase" env-default:"false"`
+	RequireUppercase    bool `yaml:"require_uppercase" env-default:"false"`
+	RequireDigit        bool `yaml:"require_digit" env-default:"false"`
+	RequireSymbol       bool `yaml:"require_symbol" env-default:"false"`
+	MinCharacterClasses int  `yaml:"min_character_classes" env-default:"0"`
+	ForbidUserInfo      bool `yaml:"forbid_user_info" env-default:"true"`
+	MinStrength         int  `yaml:"min_strength" env-default:"2"`
+	HistorySize         int  `yaml:"history_size" env-default:"5"`
+
+	MaxAge        time.Duration `yaml:"max_age" env-default:"0s"`
+	ExpiryWarning time.Duration `yaml:"expiry_warning" env-default:"336h"`
+}
+
+// BreachedPasswordsConfig points at an offline copy of a breach corpus. MinCount only applies
+// to range files, a bloom filter has it applied when it is built
+type BreachedPasswordsConfig struct {
+	Enabled  bool   `yaml:"enabled" env-default:"false"`
+	Source   string `yaml:"source" env-default:"bloom"`
+	Path     string `yaml:"path"`
+	MinCount int    `yaml:"min_count" env-default:"1"`
+}
+
+// LoginLockoutConfig slows down and then locks out password guessing against a single account.
+// A threshold of 0 turns it off. In anti-enumeration mode names that match no account are
+// locked out the same way, their failures are pruned once reset
+type LoginLockoutConfig struct {
+	Threshold     int           `yaml:"threshold" env-default:"5"`
+	BaseDelay     time.Duration `yaml:"base_delay" env-default:"1s"`
+	MaxDelay      time.Duration `yaml:"max_delay" env-default:"30s"`
+	Duration      time.Duration `yaml:"duration" env-default:"15m"`
+	MaxDuration   time.Duration `yaml:"max_duration" env-default:"24h"`
+	ResetAfter    time.Duration `yaml:"reset_after" env-default:"24h"`
+	PruneInterval time.Duration `yaml:"prune_interval" env-default:"1h"`
+}
+
+// RateLimitConfig limits the calls to the gRPC server and the plain HTTP handlers per client
+// address, per user and per gRPC method or HTTP route, such as "POST /v1/auth/step-up". Buckets
+// live in memory, or in redis to share them between instances
+type RateLimitConfig struct {
+	Enabled bool                     `yaml:"enabled" env-default:"false"`
+	Backend string                   `yaml:"backend" env-default:"memory"`
+	Redis   RedisConfig              `yaml:"redis"`
+	IP      RateLimitRule            `yaml:"ip"`
+	Subject RateLimitRule            `yaml:"subject"`
+	Methods map[string]RateLimitRule `yaml:"methods"`
+	Routes  map[string]RateLimitRule `yaml:"routes"`
+}
+
+type RedisConfig struct {
+	Addr     string `yaml:"addr" env-default:"localhost:6379"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD"`
+	DB       int    `yaml:"db" env-default:"0"`
+}
+
+// RateLimitRule lets Limit calls through every Period, in bursts of up to Burst calls
+type RateLimitRule struct {
+	Limit  int           `yaml:"limit"`
+	Period time.Duration `yaml:"period"`
+	Burst  int           `yaml:"burst"`
+}
+
+// LoginAbuseConfig spots password spraying and credential stuffing from the failed logins of
+// a source across every account. Each rule responds with slow_down, challenge or block
+type LoginAbuseConfig struct {
+	Enabled           bool           `yaml:"enabled" env-default:"false"`
+	Window            time.Duration  `yaml:"window" env-default:"15m"`
+	SlowDownDelay     time.Duration  `yaml:"slow_down_delay" env-default:"2s"`
+	PruneInterval     time.Duration  `yaml:"prune_interval" env-default:"5m"`
+	IPFailures        LoginAbuseRule `yaml:"ip_failures"`
+	NetworkFailures   LoginAbuseRule `yaml:"network_failures"`
+	DistinctUsernames LoginAbuseRule `yaml:"distinct_usernames"`
+}
+
+type LoginAbuseRule struct {
+	Threshold int    `yaml:"threshold"`
+	Response  string `yaml:"response" env-default:"slow_down"`
+}
+
+// ProofOfWorkConfig asks suspicious logins and sign-ups to solve a hashcash challenge. Work is
+// required from Threshold recent failed logins on and grows by Step bits each time they double.
+// Without a secret challenges are signed with a random key, they don't survive a restart
+// and are not shared between instances
+type ProofOfWorkConfig struct {
+	Enabled        bool          `yaml:"enabled" env-default:"false"`
+	Secret         string        `yaml:"secret" env:"POW_SECRET"`
+	TTL            time.Duration `yaml:"ttl" env-default:"5m"`
+	Threshold      int           `yaml:"threshold" env-default:"5"`
+	BaseDifficulty int           `yaml:"base_difficulty" env-default:"16"`
+	Step           int           `yaml:"step" env-default:"2"`
+	MaxDifficulty  int           `yaml:"max_difficulty" env-default:"24"`
+	PruneInterval  time.Duration `yaml:"prune_interval" env-default:"10m"`
+}
+
+// AntiEnumerationConfig makes Login and Register answer the same way whether or not an account
+// exists: unknown logins are refused like wrong passwords, after as long, and signing up with a
+// taken email or username looks successful while the address is notified by email
+type AntiEnumerationConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"false"`
 }
 
 func MustLoad() *Config {
@@ -35,6 +260,38 @@ func MustLoad() *Config {
 		panic("failed to read config: " + err.Error())
 	}
 
+	if !models.IsValidRegistrationMode(cfg.Registration.Mode) {
+		panic("unknown registration mode: " + cfg.Registration.Mode)
+	}
+
+	switch cfg.PasswordHashing.Algorithm {
+	case "argon2id", "bcrypt":
+	default:
+		panic("unknown password hashing algorithm: " + cfg.PasswordHashing.Algorithm)
+	}
+
+	if cfg.RateLimit.Enabled {
+		switch cfg.RateLimit.Backend {
+		case "memory", "redis":
+		default:
+			panic("unknown rate limit backend: " + cfg.RateLimit.Backend)
+		}
+	}
+
+	for _, rule := range []LoginAbuseRule{cfg.LoginAbuse.IPFailures, cfg.LoginAbuse.NetworkFailures, cfg.LoginAbuse.DistinctUsernames} {
+		if rule.Threshold > 0 && !abuse.IsValidResponse(rule.Response) {
+			panic("unknown login abuse response: " + rule.Response)
+		}
+	}
+
+	if cfg.BreachedPasswords.Enabled {
+		switch cfg.BreachedPasswords.Source {
+		case "bloom", "range":
+		default:
+			panic("unknown breached passwords source: " + cfg.BreachedPasswords.Source)
+		}
+	}
+
 	return &cfg
 }
 
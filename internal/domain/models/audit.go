@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// AuditEvent is an entry of the audit trail. It outlives the user it is about
+type AuditEvent struct {
+	ID        int64           `json:"id" db:"id"`
+	UserID    *uuid.UUID      `json:"user_id" db:"user_id"`
+	ActorID   *uuid.UUID      `json:"actor_id" db:"actor_id"`
+	Action    string          `json:"action" db:"action"`
+	Details   json.RawMessage `json:"details" db:"details"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Actions recorded in the audit trail
+const (
+	AuditAccountDeletionScheduled = "account.deletion_scheduled"
+	AuditAccountDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountErased            = "account.erased"
+	AuditAccountStatusChanged     = "account.status_changed"
+	AuditDataExportRequested      = "data_export.requested"
+	AuditUsernameChanged          = "account.username_changed"
+	AuditInvitationCreated        = "invitation.created"
+	AuditInvitationRevoked        = "invitation.revoked"
+	AuditAccountRegistered        = "account.registered"
+	AuditAccountCreated           = "account.created"
+	AuditPolicyPublished          = "policy.published"
+	AuditLoginLockedOut           = "account.login_locked_out"
+	AuditLoginLockoutCleared      = "account.login_lockout_cleared"
+	AuditLoginAbuseDetected       = "security.login_abuse_detected"
+)
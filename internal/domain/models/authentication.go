@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Authentication methods reported in the amr claim
+const (
+	AMRPassword = "pwd"
+	AMRMFA      = "mfa"
+)
+
+// Authentication context classes reported in the acr claim, weakest first
+const (
+	ACRPassword = "aal1"
+	ACRMFA      = "aal2"
+)
+
+// Restrictions of an access token. A restricted token only allows lifting its restriction
+const (
+	RestrictionPasswordChange   = "password_change"
+	RestrictionPasswordExpired  = "password_expired"
+	RestrictionPolicyAcceptance = "policy_acceptance"
+)
+
+var acrRanks = map[string]int{
+	ACRPassword: 1,
+	ACRMFA:      2,
+}
+
+// Authentication describes when and how the user last proved their identity
+type Authentication struct {
+	Time    time.Time
+	Methods []string
+	Level   string
+}
+
+// ACRSatisfies reports whether the level is at least as strong as the required one
+func ACRSatisfies(level, required string) bool {
+	return acrRanks[level] >= acrRanks[required]
+}
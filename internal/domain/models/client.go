@@ -0,0 +1,11 @@
+package models
+
+// Client describes the caller of a request as seen by the transport layer
+type Client struct {
+	IP             string
+	UserAgent      string
+	Fingerprint    string
+	DeviceToken    string
+	RememberDevice bool
+	ProofOfWork    string
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type TrustedDevice struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	UserID          uuid.UUID `json:"user_id" db:"user_id"`
+	Name            string    `json:"name" db:"name"`
+	FingerprintHash string    `json:"-" db:"fingerprint_hash"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	LastUsedAt      time.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt       time.Time `json:"expires_at" db:"expires_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+const (
+	ExportFormatJSON = "json"
+	ExportFormatZip  = "zip"
+)
+
+// DataExport is a requested archive of everything held about a user
+type DataExport struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Status      string     `json:"status" db:"status"`
+	Format      string     `json:"format" db:"format"`
+	Archive     []byte     `json:"-" db:"archive"`
+	Error       string     `json:"error" db:"error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ClaimedAt   *time.Time `json:"-" db:"claimed_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// PersonalData is the content of a data export. Secrets such as password hashes,
+// token hashes and device fingerprints are left out
+type PersonalData struct {
+	GeneratedAt     time.Time          `json:"generated_at"`
+	Profile         ExportedProfile    `json:"profile"`
+	UserProfile     *Profile           `json:"user_profile"`
+	UsernameHistory []UsernameChange   `json:"username_history"`
+	EmailChanges    []EmailChange      `json:"email_changes"`
+	Sessions        []Session          `json:"sessions"`
+	TrustedDevices  []TrustedDevice    `json:"trusted_devices"`
+	LoginEvents     []LoginEvent       `json:"login_events"`
+	AuditEvents     []AuditEvent       `json:"audit_events"`
+	Consents        []PolicyAcceptance `json:"consents"`
+}
+
+type ExportedProfile struct {
+	ID                  uuid.UUID     `json:"id"`
+	Username            string        `json:"username"`
+	Email               string        `json:"email"`
+	EmailVerifiedAt     *time.Time    `json:"email_verified_at"`
+	Role                string        `json:"role"`
+	Status              AccountStatus `json:"status"`
+	DeletionScheduledAt *time.Time    `json:"deletion_scheduled_at"`
+}
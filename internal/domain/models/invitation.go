@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Registration modes
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite_only"
+	RegistrationClosed     = "closed"
+)
+
+// Invitation lets up to MaxUses people register, only with Email if it is set. CreatedBy
+// is nil once the administrator who created it is erased
+type Invitation struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	CodeHash  string     `json:"-" db:"code_hash"`
+	Email     string     `json:"email" db:"email"`
+	CreatedBy *uuid.UUID `json:"created_by" db:"created_by"`
+	MaxUses   int        `json:"max_uses" db:"max_uses"`
+	Uses      int        `json:"uses" db:"uses"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+func IsValidRegistrationMode(mode string) bool {
+	switch mode {
+	case RegistrationOpen, RegistrationInviteOnly, RegistrationClosed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// LoginLockout counts the failed logins of an account since its last successful one.
+// Lockouts is how many times in a row the account got locked, each lockout lasts longer.
+// Subject is the id of the account, or the normalized name tried when there is no such
+// account, in which case UserID is the nil uuid
+type LoginLockout struct {
+	Subject        string     `json:"-" db:"subject"`
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	FailedAttempts int        `json:"failed_attempts" db:"failed_attempts"`
+	LastFailedAt   time.Time  `json:"last_failed_at" db:"last_failed_at"`
+	LockedUntil    *time.Time `json:"locked_until" db:"locked_until"`
+	Lockouts       int        `json:"lockouts" db:"lockouts"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// LoginResult holds everything issued to the client by a successful login.
+// A restricted login comes with a restricted access token and no refresh token
+type LoginResult struct {
+	AccessToken  string
+	RefreshToken string
+	DeviceToken  string
+	Restriction  string
+
+	// PasswordExpiresAt is set once the password is about to expire
+	PasswordExpiresAt *time.Time
+}
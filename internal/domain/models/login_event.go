@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// LoginEvent is a single login attempt of a known user together with the risk assessment made for it
+type LoginEvent struct {
+	UserID        uuid.UUID       `json:"user_id" db:"user_id"`
+	IP            string          `json:"ip" db:"ip"`
+	Network       string          `json:"network" db:"network"`
+	UserAgent     string          `json:"user_agent" db:"user_agent"`
+	DeviceTrusted bool            `json:"device_trusted" db:"device_trusted"`
+	Success       bool            `json:"success" db:"success"`
+	RiskScore     int             `json:"risk_score" db:"risk_score"`
+	RiskDecision  string          `json:"risk_decision" db:"risk_decision"`
+	Signals       json.RawMessage `json:"signals" db:"signals"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// LoginStats summarises the login history of a user relative to the current attempt
+type LoginStats struct {
+	Successes      int
+	KnownIP        bool
+	KnownNetwork   bool
+	RecentFailures int
+	LastSuccessAt  time.Time
+}
+
+// LoginFailure is a failed login of any account, known or not, kept to spot sources that
+// try many accounts. The username tried is only kept hashed
+type LoginFailure struct {
+	IP           string    `json:"ip" db:"ip"`
+	Network      string    `json:"network" db:"network"`
+	UsernameHash string    `json:"username_hash" db:"username_hash"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// LoginFailureStats counts the recent failed logins of a source across every account
+type LoginFailureStats struct {
+	IPFailures        int
+	NetworkFailures   int
+	DistinctUsernames int
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Kinds of policy documents users accept
+const (
+	PolicyTerms   = "terms"
+	PolicyPrivacy = "privacy"
+)
+
+// PolicyDocument is a published version of the terms of service or the privacy policy.
+// A new mandatory version has to be accepted before the user can go on using their account
+type PolicyDocument struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Kind        string    `json:"kind" db:"kind"`
+	Version     string    `json:"version" db:"version"`
+	URL         string    `json:"url" db:"url"`
+	Mandatory   bool      `json:"mandatory" db:"mandatory"`
+	PublishedAt time.Time `json:"published_at" db:"published_at"`
+}
+
+// PolicyAcceptance proves that a user accepted a policy document, when and from where
+type PolicyAcceptance struct {
+	DocumentID uuid.UUID `json:"document_id" db:"document_id"`
+	Kind       string    `json:"kind" db:"kind"`
+	Version    string    `json:"version" db:"version"`
+	AcceptedAt time.Time `json:"accepted_at" db:"accepted_at"`
+	IP         string    `json:"ip" db:"ip"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+}
+
+func IsValidPolicyKind(kind string) bool {
+	switch kind {
+	case PolicyTerms, PolicyPrivacy:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Profile field paths accepted in update masks
+const (
+	ProfileDisplayName = "display_name"
+	ProfileAvatarURL   = "avatar_url"
+	ProfileLocale      = "locale"
+	ProfileTimezone    = "timezone"
+	ProfileMetadata    = "metadata"
+)
+
+// Profile holds what a user tells about themselves, Version grows with every update
+// and is zero while the user never saved a profile
+type Profile struct {
+	UserID      uuid.UUID         `json:"user_id" db:"user_id"`
+	DisplayName string            `json:"display_name" db:"display_name"`
+	AvatarURL   string            `json:"avatar_url" db:"avatar_url"`
+	Locale      string            `json:"locale" db:"locale"`
+	Timezone    string            `json:"timezone" db:"timezone"`
+	Metadata    map[string]string `json:"metadata" db:"metadata"`
+	Version     int64             `json:"version" db:"version"`
+	UpdatedAt   *time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// ProfileUpdate carries new values for the fields named in Paths, other fields are ignored
+type ProfileUpdate struct {
+	Paths   []string
+	Profile Profile
+	Version int64
+}
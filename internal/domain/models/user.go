@@ -5,6 +5,19 @@ import (
 	"time"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Account statuses, every status but active keeps the user from logging in
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusLocked    = "locked"
+	StatusBanned    = "banned"
+)
+
 type User struct {
 	ID        uuid.UUID `json:"id" db:"id"`
 	Username  string    `json:"username" db:"username"`
@@ -12,4 +25,40 @@ type User struct {
 	Password  string    `json:"password" db:"password"` // Хешированный пароль
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	EmailVerifiedAt     *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at" db:"deletion_scheduled_at"`
+
+	Role   string        `json:"role" db:"role"`
+	Status AccountStatus `json:"status"`
+
+	MustChangePassword bool      `json:"must_change_password" db:"must_change_password"`
+	PasswordChangedAt  time.Time `json:"password_changed_at" db:"password_changed_at"`
+}
+
+// AccountStatus tells whether a user may use their account and, if not, who decided so and why
+type AccountStatus struct {
+	Status    string     `json:"status" db:"status"`
+	Reason    string     `json:"reason" db:"status_reason"`
+	ActorID   *uuid.UUID `json:"actor_id" db:"status_actor_id"`
+	ExpiresAt *time.Time `json:"expires_at" db:"status_expires_at"`
+	ChangedAt *time.Time `json:"changed_at" db:"status_changed_at"`
+}
+
+// Effective returns the status in force at the given time, an expired status is active again
+func (s AccountStatus) Effective(now time.Time) string {
+	if s.Status == "" || (s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)) {
+		return StatusActive
+	}
+
+	return s.Status
+}
+
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusActive, StatusSuspended, StatusLocked, StatusBanned:
+		return true
+	default:
+		return false
+	}
 }
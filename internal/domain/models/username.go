@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// UsernameChange records a username a user gave up. Nobody can take it before ReservedUntil
+type UsernameChange struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	UserID        uuid.UUID `json:"user_id" db:"user_id"`
+	Username      string    `json:"username" db:"username"`
+	ChangedAt     time.Time `json:"changed_at" db:"changed_at"`
+	ReservedUntil time.Time `json:"reserved_until" db:"reserved_until"`
+}
@@ -0,0 +1,132 @@
+package account
+
+import (
+	accountv1 "AuthService/gen/go/account"
+	"AuthService/internal/domain/models"
+	"AuthService/internal/services/account"
+	"AuthService/internal/services/auth"
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strings"
+)
+
+const mdAuthorization = "authorization"
+
+// Account is the profile part of the account service
+type Account interface {
+	GetProfile(ctx context.Context, accessToken string) (profile *models.Profile, err error)
+	UpdateProfile(ctx context.Context, accessToken string, update models.ProfileUpdate) (profile *models.Profile, err error)
+}
+
+type serverAPI struct {
+	accountv1.UnimplementedAccountServiceServer
+	account Account
+}
+
+func Register(gRPC *grpc.Server, account Account) {
+	accountv1.RegisterAccountServiceServer(gRPC, &serverAPI{account: account})
+}
+
+func (s *serverAPI) GetProfile(ctx context.Context, _ *accountv1.GetProfileRequest) (*accountv1.Profile, error) {
+	accessToken := accessTokenFromContext(ctx)
+	if accessToken == "" {
+		return nil, status.Error(codes.Unauthenticated, "access token is empty")
+	}
+
+	profile, err := s.account.GetProfile(ctx, accessToken)
+	if err != nil {
+		return nil, profileError(err)
+	}
+
+	return toProto(profile), nil
+}
+
+func (s *serverAPI) UpdateProfile(ctx context.Context, req *accountv1.UpdateProfileRequest) (*accountv1.Profile, error) {
+	accessToken := accessTokenFromContext(ctx)
+	if accessToken == "" {
+		return nil, status.Error(codes.Unauthenticated, "access token is empty")
+	}
+
+	profile, err := s.account.UpdateProfile(ctx, accessToken, models.ProfileUpdate{
+		Paths:   req.GetUpdateMask().GetPaths(),
+		Profile: fromProto(req.GetProfile()),
+		Version: req.GetVersion(),
+	})
+	if err != nil {
+		return nil, profileError(err)
+	}
+
+	return toProto(profile), nil
+}
+
+// profileError turns an error of the account service into the status it stands for. A
+// restricted access token is refused like the auth server refuses it
+func profileError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, auth.ErrPasswordChangeRequired):
+		return status.Error(codes.PermissionDenied, "password change required")
+	case errors.Is(err, auth.ErrPasswordExpired):
+		return status.Error(codes.PermissionDenied, "password expired")
+	case errors.Is(err, auth.ErrPolicyAcceptanceRequired):
+		return status.Error(codes.PermissionDenied, "policy acceptance required")
+	case errors.Is(err, account.ErrInvalidProfile), errors.Is(err, account.ErrUnknownField):
+		return status.Error(codes.InvalidArgument, errors.Unwrap(err).Error())
+	case errors.Is(err, account.ErrVersionConflict):
+		return status.Error(codes.Aborted, "profile was changed by another request")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func toProto(profile *models.Profile) *accountv1.Profile {
+	result := &accountv1.Profile{
+		UserId:      profile.UserID.String(),
+		DisplayName: profile.DisplayName,
+		AvatarUrl:   profile.AvatarURL,
+		Locale:      profile.Locale,
+		Timezone:    profile.Timezone,
+		Metadata:    profile.Metadata,
+		Version:     profile.Version,
+	}
+
+	if profile.UpdatedAt != nil {
+		result.UpdatedAt = timestamppb.New(*profile.UpdatedAt)
+	}
+
+	return result
+}
+
+// fromProto takes the fields a client may set, the others belong to the server
+func fromProto(profile *accountv1.Profile) models.Profile {
+	return models.Profile{
+		DisplayName: profile.GetDisplayName(),
+		AvatarURL:   profile.GetAvatarUrl(),
+		Locale:      profile.GetLocale(),
+		Timezone:    profile.GetTimezone(),
+		Metadata:    profile.GetMetadata(),
+	}
+}
+
+// accessTokenFromContext returns the bearer token of the authorization metadata
+func accessTokenFromContext(ctx context.Context) string {
+	const prefix = "bearer "
+
+	values := metadata.ValueFromIncomingContext(ctx, mdAuthorization)
+	if len(values) == 0 {
+		return ""
+	}
+
+	value := values[0]
+	if len(value) < len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(value[len(prefix):])
+}
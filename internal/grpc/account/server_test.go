@@ -0,0 +1,197 @@
+package account
+
+import (
+	accountv1 "AuthService/gen/go/account"
+	"AuthService/internal/domain/models"
+	"AuthService/internal/services/account"
+	"AuthService/internal/services/auth"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubAccount answers with profile or fails with err, wrapped like the account service wraps it,
+// and remembers the update it was given
+type stubAccount struct {
+	profile *models.Profile
+	err     error
+	token   string
+	update  models.ProfileUpdate
+}
+
+func (s *stubAccount) GetProfile(_ context.Context, accessToken string) (*models.Profile, error) {
+	s.token = accessToken
+	if s.err != nil {
+		return nil, fmt.Errorf("account.GetProfile: %w", s.err)
+	}
+
+	return s.profile, nil
+}
+
+func (s *stubAccount) UpdateProfile(_ context.Context, accessToken string, update models.ProfileUpdate) (*models.Profile, error) {
+	s.token = accessToken
+	s.update = update
+	if s.err != nil {
+		return nil, fmt.Errorf("account.UpdateProfile: %w", s.err)
+	}
+
+	return s.profile, nil
+}
+
+func withToken(authorization string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(mdAuthorization, authorization))
+}
+
+func TestProfileErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{
+			name:        "no access token",
+			ctx:         context.Background(),
+			wantCode:    codes.Unauthenticated,
+			wantMessage: "access token is empty",
+		},
+		{
+			name:        "not a bearer token",
+			ctx:         withToken("Basic dXNlcjpwYXNz"),
+			wantCode:    codes.Unauthenticated,
+			wantMessage: "access token is empty",
+		},
+		{
+			name:        "invalid token",
+			ctx:         withToken("Bearer token"),
+			err:         fmt.Errorf("auth.Authenticate: %w", auth.ErrInvalidToken),
+			wantCode:    codes.Unauthenticated,
+			wantMessage: "invalid token",
+		},
+		{
+			name:        "password change required",
+			ctx:         withToken("Bearer token"),
+			err:         fmt.Errorf("auth.Authenticate: %w", auth.ErrPasswordChangeRequired),
+			wantCode:    codes.PermissionDenied,
+			wantMessage: "password change required",
+		},
+		{
+			name:        "policy acceptance required",
+			ctx:         withToken("Bearer token"),
+			err:         fmt.Errorf("auth.Authenticate: %w", auth.ErrPolicyAcceptanceRequired),
+			wantCode:    codes.PermissionDenied,
+			wantMessage: "policy acceptance required",
+		},
+		{
+			name:        "invalid profile",
+			ctx:         withToken("Bearer token"),
+			err:         fmt.Errorf("%w: display name is too long", account.ErrInvalidProfile),
+			wantCode:    codes.InvalidArgument,
+			wantMessage: "invalid profile: display name is too long",
+		},
+		{
+			name:        "version conflict",
+			ctx:         withToken("Bearer token"),
+			err:         account.ErrVersionConflict,
+			wantCode:    codes.Aborted,
+			wantMessage: "profile was changed by another request",
+		},
+		{
+			name:        "anything else",
+			ctx:         withToken("Bearer token"),
+			err:         errors.New("connection refused"),
+			wantCode:    codes.Internal,
+			wantMessage: "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &serverAPI{account: &stubAccount{err: tt.err}}
+
+			calls := map[string]func() error{
+				"GetProfile": func() error {
+					_, err := s.GetProfile(tt.ctx, &accountv1.GetProfileRequest{})
+					return err
+				},
+				"UpdateProfile": func() error {
+					_, err := s.UpdateProfile(tt.ctx, &accountv1.UpdateProfileRequest{})
+					return err
+				},
+			}
+
+			for method, call := range calls {
+				st := status.Convert(call())
+
+				if st.Code() != tt.wantCode {
+					t.Errorf("%s: code %s, want %s", method, st.Code(), tt.wantCode)
+				}
+
+				if st.Message() != tt.wantMessage {
+					t.Errorf("%s: message %q, want %q", method, st.Message(), tt.wantMessage)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	updatedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	saved := &models.Profile{
+		UserID:      uuid.MustParse("7f9c2a4e-5b1d-4c3e-9a8f-1e2d3c4b5a69"),
+		DisplayName: "Ann",
+		Locale:      "en-GB",
+		Metadata:    map[string]string{"team": "blue"},
+		Version:     4,
+		UpdatedAt:   &updatedAt,
+	}
+
+	stub := &stubAccount{profile: saved}
+	s := &serverAPI{account: stub}
+
+	got, err := s.UpdateProfile(withToken("Bearer token"), &accountv1.UpdateProfileRequest{
+		Profile: &accountv1.Profile{
+			UserId:      "someone else",
+			DisplayName: "Ann",
+			Locale:      "en-GB",
+			Metadata:    map[string]string{"team": "blue"},
+			Version:     99,
+		},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"display_name", "locale", "metadata"}},
+		Version:    3,
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+
+	if stub.token != "token" {
+		t.Errorf("access token %q, want %q", stub.token, "token")
+	}
+
+	wantUpdate := models.ProfileUpdate{
+		Paths: []string{"display_name", "locale", "metadata"},
+		// The user and the version of the profile are not the client's to set
+		Profile: models.Profile{DisplayName: "Ann", Locale: "en-GB", Metadata: map[string]string{"team": "blue"}},
+		Version: 3,
+	}
+	if !reflect.DeepEqual(stub.update, wantUpdate) {
+		t.Errorf("update %+v, want %+v", stub.update, wantUpdate)
+	}
+
+	if got.GetUserId() != saved.UserID.String() || got.GetDisplayName() != "Ann" || got.GetVersion() != 4 {
+		t.Errorf("profile %v does not match the saved one", got)
+	}
+
+	if !got.GetUpdatedAt().AsTime().Equal(updatedAt) {
+		t.Errorf("updated_at %v, want %v", got.GetUpdatedAt().AsTime(), updatedAt)
+	}
+}
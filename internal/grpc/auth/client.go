@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/clientip"
+	"AuthService/internal/lib/jwt"
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"strings"
+)
+
+// Metadata keys understood by the auth server in addition to the request messages
+const (
+	mdForwardedFor      = "x-forwarded-for"
+	mdUserAgent         = "user-agent"
+	mdGatewayUserAgent  = "grpcgateway-user-agent"
+	mdDeviceFingerprint = "x-device-fingerprint"
+	mdDeviceToken       = "x-device-token"
+	mdRememberDevice    = "x-remember-device"
+	mdAuthorization     = "authorization"
+	mdAccountStatus     = "x-account-status"
+	mdInvitationCode    = "x-invitation-code"
+	mdLoginRestriction  = "x-login-restriction"
+	mdPasswordExpiresAt = "x-password-expires-at"
+	mdRetryAfter        = "retry-after"
+	mdPowSolution       = "x-pow-solution"
+	mdPowChallenge      = "x-pow-challenge"
+	mdPowDifficulty     = "x-pow-difficulty"
+)
+
+// gatewayHeaders are the request headers the HTTP gateway passes on as metadata of the same name
+var gatewayHeaders = map[string]bool{
+	mdDeviceFingerprint: true,
+	mdDeviceToken:       true,
+	mdRememberDevice:    true,
+	mdInvitationCode:    true,
+	mdPowSolution:       true,
+}
+
+// HeaderMatcher tells the HTTP gateway which request headers to pass on to the auth server.
+// Headers of its own are passed on like the gateway does by default
+func HeaderMatcher(key string) (string, bool) {
+	if name := strings.ToLower(key); gatewayHeaders[name] {
+		return name, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// clientFromContext collects what is known about the caller from the peer and the incoming metadata
+func clientFromContext(ctx context.Context, proxies clientip.Proxies) models.Client {
+	client := models.Client{IP: ClientIP(ctx, proxies)}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return client
+	}
+
+	client.UserAgent = firstValue(md, mdGatewayUserAgent)
+	if client.UserAgent == "" {
+		client.UserAgent = firstValue(md, mdUserAgent)
+	}
+
+	client.Fingerprint = firstValue(md, mdDeviceFingerprint)
+	client.DeviceToken = firstValue(md, mdDeviceToken)
+	client.RememberDevice = firstValue(md, mdRememberDevice) == "true"
+	client.ProofOfWork = firstValue(md, mdPowSolution)
+
+	return client
+}
+
+// ClientIP returns the address of the caller. The x-forwarded-for metadata is only believed
+// when the direct peer is a trusted proxy, such as the HTTP gateway
+func ClientIP(ctx context.Context, proxies clientip.Proxies) string {
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	return proxies.Resolve(addr, md.Get(mdForwardedFor))
+}
+
+// Subject returns the user of a valid access token in the authorization metadata, empty for
+// anonymous calls
+func Subject(ctx context.Context) string {
+	token := accessTokenFromContext(ctx)
+	if token == "" {
+		return ""
+	}
+
+	claims, err := jwt.ParseAccessToken(token)
+	if err != nil {
+		return ""
+	}
+
+	return claims.UserID
+}
+
+// invitationCodeFromContext returns the invitation code a registration is made with, if any
+func invitationCodeFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return firstValue(md, mdInvitationCode)
+}
+
+// accessTokenFromContext returns the bearer token of the authorization metadata
+func accessTokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	const prefix = "bearer "
+
+	value := firstValue(md, mdAuthorization)
+	if len(value) < len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(value[len(prefix):])
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
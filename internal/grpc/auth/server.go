@@ -1,13 +1,21 @@
 package auth
 
 import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/clientip"
+	"AuthService/internal/lib/password"
 	"AuthService/internal/services/auth"
 	"context"
 	"errors"
 	ssov1 "github.com/ryzhy1/protos/gen/go/sso"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"strconv"
+	"time"
 )
 
 type Auth interface {
@@ -15,12 +23,15 @@ type Auth interface {
 		ctx context.Context,
 		email string,
 		password string,
-	) (accessToken, refreshToken string, err error)
+		client models.Client,
+	) (result *models.LoginResult, err error)
 	Register(
 		ctx context.Context,
 		login string,
 		email string,
 		password string,
+		invitationCode string,
+		client models.Client,
 	) (userID string, err error)
 
 	UpdateUserEmail(
@@ -31,11 +42,15 @@ type Auth interface {
 	) (message string, err error)
 
 	UpdateUserPassword(ctx context.Context, userId, oldPassword, newPassword string) (message string, err error)
+
+	RequireRecentAuth(ctx context.Context, accessToken, userId string) error
+	RequirePasswordChangeAuth(ctx context.Context, accessToken, userId string) error
 }
 
 type serverAPI struct {
 	ssov1.UnimplementedAuthServiceServer
-	auth Auth
+	auth    Auth
+	proxies clientip.Proxies
 }
 
 const (
@@ -45,8 +60,8 @@ const (
 	ErrNoActiveSession    = "user already logged out"
 )
 
-func Register(gRPC *grpc.Server, auth Auth) {
-	ssov1.RegisterAuthServiceServer(gRPC, &serverAPI{auth: auth})
+func Register(gRPC *grpc.Server, auth Auth, proxies clientip.Proxies) {
+	ssov1.RegisterAuthServiceServer(gRPC, &serverAPI{auth: auth, proxies: proxies})
 }
 
 func (s *serverAPI) Login(ctx context.Context, req *ssov1.LoginRequest) (*ssov1.LoginResponse, error) {
@@ -58,14 +73,68 @@ func (s *serverAPI) Login(ctx context.Context, req *ssov1.LoginRequest) (*ssov1.
 		return nil, status.Error(codes.InvalidArgument, "password is empty")
 	}
 
-	accessToken, refreshToken, err := s.auth.Login(ctx, req.GetInput(), req.GetPassword())
+	result, err := s.auth.Login(ctx, req.GetInput(), req.GetPassword(), clientFromContext(ctx, s.proxies))
 	if err != nil {
+		if errors.Is(err, auth.ErrMFARequired) {
+			return nil, status.Error(codes.Unauthenticated, "additional verification required")
+		}
+
+		if errors.Is(err, auth.ErrLoginBlocked) {
+			return nil, status.Error(codes.PermissionDenied, "login blocked")
+		}
+
+		var lockoutErr *auth.LockoutError
+		if errors.As(err, &lockoutErr) {
+			return nil, lockoutError(ctx, lockoutErr)
+		}
+
+		var powErr *auth.ProofOfWorkError
+		if errors.As(err, &powErr) {
+			return nil, proofOfWorkError(ctx, powErr)
+		}
+
+		if errors.Is(err, auth.ErrEmailNotVerified) {
+			return nil, status.Error(codes.FailedPrecondition, "email not verified")
+		}
+
+		if errors.Is(err, auth.ErrAccountSuspended) {
+			return nil, accountStatusError(ctx, "suspended")
+		}
+
+		if errors.Is(err, auth.ErrAccountLocked) {
+			return nil, accountStatusError(ctx, "locked")
+		}
+
+		if errors.Is(err, auth.ErrAccountBanned) {
+			return nil, accountStatusError(ctx, "banned")
+		}
+
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
+	if result.DeviceToken != "" {
+		if err := grpc.SetHeader(ctx, metadata.Pairs(mdDeviceToken, result.DeviceToken)); err != nil {
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	// The response has no field for it, a restricted login is announced in the header
+	if result.Restriction != "" {
+		if err := grpc.SetHeader(ctx, metadata.Pairs(mdLoginRestriction, result.Restriction)); err != nil {
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	if result.PasswordExpiresAt != nil {
+		expiresAt := result.PasswordExpiresAt.UTC().Format(time.RFC3339)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(mdPasswordExpiresAt, expiresAt)); err != nil {
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
 	return &ssov1.LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
 	}, nil
 }
 
@@ -82,8 +151,30 @@ func (s *serverAPI) Register(ctx context.Context, req *ssov1.RegisterRequest) (*
 		return nil, status.Error(codes.InvalidArgument, "password is empty")
 	}
 
-	userID, err := s.auth.Register(ctx, req.GetUsername(), req.GetEmail(), req.GetPassword())
+	userID, err := s.auth.Register(ctx, req.GetUsername(), req.GetEmail(), req.GetPassword(), invitationCodeFromContext(ctx), clientFromContext(ctx, s.proxies))
 	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyError(policyErr)
+		}
+
+		var powErr *auth.ProofOfWorkError
+		if errors.As(err, &powErr) {
+			return nil, proofOfWorkError(ctx, powErr)
+		}
+
+		if errors.Is(err, auth.ErrRegistrationClosed) {
+			return nil, status.Error(codes.FailedPrecondition, "registration is closed")
+		}
+
+		if errors.Is(err, auth.ErrInvitationRequired) {
+			return nil, status.Error(codes.PermissionDenied, "invitation required")
+		}
+
+		if errors.Is(err, auth.ErrInvalidInvitation) {
+			return nil, status.Error(codes.PermissionDenied, "invitation is invalid or expired")
+		}
+
 		if errors.Is(err, auth.ErrUserAlreadyExists) {
 			return nil, status.Error(codes.AlreadyExists, "user already exists")
 		}
@@ -109,12 +200,20 @@ func (s *serverAPI) UpdateEmail(ctx context.Context, req *ssov1.EmailRequest) (*
 		return nil, status.Error(codes.InvalidArgument, "new email is empty")
 	}
 
+	if err := s.requireRecentAuth(ctx, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
 	message, err := s.auth.UpdateUserEmail(ctx, req.GetUserId(), req.GetOldEmail(), req.GetNewEmail())
 	if err != nil {
 		if errors.Is(err, auth.ErrUserNotFound) {
 			return nil, status.Error(codes.NotFound, "user not found")
 		}
 
+		if errors.Is(err, auth.ErrEmailAlreadyTaken) {
+			return nil, status.Error(codes.AlreadyExists, "email already taken")
+		}
+
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
@@ -137,8 +236,22 @@ func (s *serverAPI) UpdatePassword(ctx context.Context, req *ssov1.PasswordReque
 		return nil, status.Error(codes.InvalidArgument, "new password is empty")
 	}
 
+	// A token restricted to a password change is good enough here
+	if err := s.requireAuth(ctx, req.GetUserId(), s.auth.RequirePasswordChangeAuth); err != nil {
+		return nil, err
+	}
+
 	message, err := s.auth.UpdateUserPassword(ctx, req.GetUserId(), req.GetOldPassword(), req.GetNewPassword())
 	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyError(policyErr)
+		}
+
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
+		}
+
 		if errors.Is(err, auth.ErrUserNotFound) {
 			return nil, status.Error(codes.NotFound, "user not found")
 		} else {
@@ -151,6 +264,133 @@ func (s *serverAPI) UpdatePassword(ctx context.Context, req *ssov1.PasswordReque
 	}, nil
 }
 
+// accountStatusError refuses a non-active account. The status is also sent as trailer
+// metadata so clients don't have to parse the message
+func accountStatusError(ctx context.Context, accountStatus string) error {
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(mdAccountStatus, accountStatus))
+
+	return status.Error(codes.PermissionDenied, "account "+accountStatus)
+}
+
+// lockoutError tells how long to wait before trying to log in again, in seconds in the
+// retry-after trailer and as retry info in the status details
+func lockoutError(ctx context.Context, lockoutErr *auth.LockoutError) error {
+	retryAfter := time.Until(lockoutErr.RetryAt).Round(time.Second)
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(mdRetryAfter, strconv.Itoa(int(retryAfter.Seconds()))))
+
+	message := "too many failed login attempts, try again later"
+	if lockoutErr.Locked {
+		message = "account temporarily locked after too many failed login attempts"
+	}
+
+	st := status.New(codes.ResourceExhausted, message)
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// proofOfWorkError hands out the challenge to solve before the request is retried, in the
+// trailer metadata and as error info in the status details
+func proofOfWorkError(ctx context.Context, powErr *auth.ProofOfWorkError) error {
+	difficulty := strconv.Itoa(powErr.Difficulty)
+
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(mdPowChallenge, powErr.Challenge, mdPowDifficulty, difficulty))
+
+	st := status.New(codes.FailedPrecondition, "proof of work required")
+
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: "PROOF_OF_WORK_REQUIRED",
+		Domain: "auth",
+		Metadata: map[string]string{
+			"challenge":  powErr.Challenge,
+			"difficulty": difficulty,
+		},
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// passwordPolicyError lists the broken password rules in the status details, as field
+// violations for display and as error info metadata keyed by rule for clients to match on
+func passwordPolicyError(policyErr *password.PolicyError) error {
+	st := status.New(codes.InvalidArgument, "password does not satisfy the policy")
+
+	badRequest := &errdetails.BadRequest{}
+	info := &errdetails.ErrorInfo{
+		Reason:   "PASSWORD_POLICY_VIOLATION",
+		Domain:   "auth",
+		Metadata: make(map[string]string, len(policyErr.Violations)),
+	}
+
+	for _, v := range policyErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       "password",
+			Description: v.Message,
+		})
+		info.Metadata[v.Rule] = v.Message
+	}
+
+	detailed, err := st.WithDetails(badRequest, info)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// requireRecentAuth guards sensitive RPCs with the step-up policy of the auth service
+func (s *serverAPI) requireRecentAuth(ctx context.Context, userId string) error {
+	return s.requireAuth(ctx, userId, s.auth.RequireRecentAuth)
+}
+
+func (s *serverAPI) requireAuth(
+	ctx context.Context,
+	userId string,
+	check func(ctx context.Context, accessToken, userId string) error,
+) error {
+	accessToken := accessTokenFromContext(ctx)
+	if accessToken == "" {
+		return status.Error(codes.Unauthenticated, "access token is empty")
+	}
+
+	if err := check(ctx, accessToken, userId); err != nil {
+		if errors.Is(err, auth.ErrPasswordChangeRequired) {
+			return status.Error(codes.PermissionDenied, "password change required")
+		}
+
+		if errors.Is(err, auth.ErrPasswordExpired) {
+			return status.Error(codes.PermissionDenied, "password expired")
+		}
+
+		if errors.Is(err, auth.ErrPolicyAcceptanceRequired) {
+			return status.Error(codes.PermissionDenied, "policy acceptance required")
+		}
+
+		if errors.Is(err, auth.ErrStepUpRequired) {
+			return status.Error(codes.PermissionDenied, "step-up authentication required")
+		}
+
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return nil
+}
+
 //func validateRegister(req *ssov1.RegisterRequest) error {
 //	if req.GetUsername() == "" {
 //		return status.Error(codes.InvalidArgument, "login is empty")
@@ -0,0 +1,76 @@
+package interceptors
+
+import (
+	authgrpc "AuthService/internal/grpc/auth"
+	"AuthService/internal/lib/clientip"
+	"AuthService/internal/lib/ratelimit"
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const mdRetryAfter = "retry-after"
+
+// RateLimit refuses calls over the limits with ResourceExhausted, telling when to retry in the
+// retry-after trailer, in seconds, and as retry info in the status details. A failing limiter
+// is logged and lets calls through. Callers are told apart by the address the trusted proxies
+// forward for them and by the user subject finds in the call, empty for anonymous calls
+func RateLimit(
+	log *slog.Logger,
+	limiter ratelimit.Limiter,
+	limits ratelimit.Limits,
+	proxies clientip.Proxies,
+	subject func(ctx context.Context) string,
+) grpc.UnaryServerInterceptor {
+	log = log.With(slog.String("op", "interceptors.RateLimit"))
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		buckets := limits.Buckets(authgrpc.ClientIP(ctx, proxies), subject(ctx), info.FullMethod)
+
+		result, err := limiter.Allow(ctx, buckets)
+		if err != nil {
+			log.Error("failed to check rate limit", "method", info.FullMethod, "error", err)
+
+			return handler(ctx, req)
+		}
+
+		if !result.Allowed {
+			log.Info("rate limit exceeded", "key", result.Exhausted.Key, "method", info.FullMethod)
+
+			return nil, rateLimitError(ctx, result.Exhausted.Dimension, result.RetryAfter)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func rateLimitError(ctx context.Context, dimension string, retryAfter time.Duration) error {
+	seconds := int(retryAfter.Round(time.Second).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(mdRetryAfter, strconv.Itoa(seconds)))
+
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded, try again later")
+
+	detailed, err := st.WithDetails(
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(seconds) * time.Second)},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     dimension,
+			Description: "too many requests per " + dimension,
+		}}},
+	)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
@@ -0,0 +1,98 @@
+package account
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/http/api"
+	"AuthService/internal/services/account"
+	"AuthService/internal/services/auth"
+	"context"
+	"errors"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+	"strings"
+)
+
+const profilePath = "/v1/account/profile"
+
+// Account is the profile part of the account service
+type Account interface {
+	GetProfile(ctx context.Context, accessToken string) (profile *models.Profile, err error)
+	UpdateProfile(ctx context.Context, accessToken string, update models.ProfileUpdate) (profile *models.Profile, err error)
+}
+
+// updateProfileRequest follows the JSON mapping of a protobuf update with a field mask
+type updateProfileRequest struct {
+	Profile    models.Profile `json:"profile"`
+	UpdateMask string         `json:"update_mask"`
+	Version    int64          `json:"version"`
+}
+
+// Register adds the profile handlers to the gateway mux
+func Register(mux *runtime.ServeMux, account Account) error {
+	if err := mux.HandlePath(http.MethodGet, profilePath, getProfile(account)); err != nil {
+		return err
+	}
+
+	return mux.HandlePath(http.MethodPatch, profilePath, updateProfile(account))
+}
+
+func getProfile(account Account) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		profile, err := account.GetProfile(r.Context(), api.BearerToken(r))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		api.WriteJSON(w, profile)
+	}
+}
+
+func updateProfile(account Account) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req updateProfileRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		var paths []string
+		for _, path := range strings.Split(req.UpdateMask, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				paths = append(paths, path)
+			}
+		}
+
+		profile, err := account.UpdateProfile(r.Context(), api.BearerToken(r), models.ProfileUpdate{
+			Paths:   paths,
+			Profile: req.Profile,
+			Version: req.Version,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		api.WriteJSON(w, profile)
+	}
+}
+
+// writeError answers a failed profile call with the status its error stands for. A restricted
+// access token is refused like the auth handlers refuse it
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+	case errors.Is(err, auth.ErrPasswordChangeRequired):
+		http.Error(w, "password change required", http.StatusForbidden)
+	case errors.Is(err, auth.ErrPasswordExpired):
+		http.Error(w, "password expired", http.StatusForbidden)
+	case errors.Is(err, auth.ErrPolicyAcceptanceRequired):
+		http.Error(w, "policy acceptance required", http.StatusForbidden)
+	case errors.Is(err, account.ErrInvalidProfile), errors.Is(err, account.ErrUnknownField):
+		http.Error(w, errors.Unwrap(err).Error(), http.StatusBadRequest)
+	case errors.Is(err, account.ErrVersionConflict):
+		http.Error(w, "profile was changed by another request", http.StatusConflict)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,114 @@
+package account
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/services/account"
+	"AuthService/internal/services/auth"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubAccount fails every call with err, wrapped like the account service wraps it
+type stubAccount struct {
+	err error
+}
+
+func (s stubAccount) GetProfile(context.Context, string) (*models.Profile, error) {
+	return nil, fmt.Errorf("account.GetProfile: %w", s.err)
+}
+
+func (s stubAccount) UpdateProfile(context.Context, string, models.ProfileUpdate) (*models.Profile, error) {
+	return nil, fmt.Errorf("account.UpdateProfile: %w", s.err)
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "invalid token",
+			err:        fmt.Errorf("auth.Authenticate: %w", auth.ErrInvalidToken),
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "invalid token",
+		},
+		{
+			name:       "password change required",
+			err:        fmt.Errorf("auth.Authenticate: %w", auth.ErrPasswordChangeRequired),
+			wantStatus: http.StatusForbidden,
+			wantBody:   "password change required",
+		},
+		{
+			name:       "password expired",
+			err:        fmt.Errorf("auth.Authenticate: %w", auth.ErrPasswordExpired),
+			wantStatus: http.StatusForbidden,
+			wantBody:   "password expired",
+		},
+		{
+			name:       "policy acceptance required",
+			err:        fmt.Errorf("auth.Authenticate: %w", auth.ErrPolicyAcceptanceRequired),
+			wantStatus: http.StatusForbidden,
+			wantBody:   "policy acceptance required",
+		},
+		{
+			name:       "invalid profile",
+			err:        fmt.Errorf("%w: display name is too long", account.ErrInvalidProfile),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid profile: display name is too long",
+		},
+		{
+			name:       "unknown field",
+			err:        fmt.Errorf("%w: email", account.ErrUnknownField),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "unknown profile field: email",
+		},
+		{
+			name:       "version conflict",
+			err:        account.ErrVersionConflict,
+			wantStatus: http.StatusConflict,
+			wantBody:   "profile was changed by another request",
+		},
+		{
+			name:       "anything else",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := []struct {
+				method string
+				body   string
+				call   func(http.ResponseWriter, *http.Request, map[string]string)
+			}{
+				{method: http.MethodGet, call: getProfile(stubAccount{err: tt.err})},
+				{method: http.MethodPatch, body: `{"update_mask":"display_name"}`, call: updateProfile(stubAccount{err: tt.err})},
+			}
+
+			for _, h := range handlers {
+				r := httptest.NewRequest(h.method, profilePath, strings.NewReader(h.body))
+				r.Header.Set("Authorization", "Bearer token")
+				w := httptest.NewRecorder()
+
+				h.call(w, r, nil)
+
+				if w.Code != tt.wantStatus {
+					t.Errorf("%s: status %d, want %d", h.method, w.Code, tt.wantStatus)
+				}
+
+				if body := strings.TrimSpace(w.Body.String()); body != tt.wantBody {
+					t.Errorf("%s: body %q, want %q", h.method, body, tt.wantBody)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/clientip"
+	"AuthService/internal/lib/jwt"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// BearerToken returns the token of the authorization header, empty if there is none
+func BearerToken(r *http.Request) string {
+	const prefix = "bearer "
+
+	value := r.Header.Get("Authorization")
+	if len(value) < len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(value[len(prefix):])
+}
+
+// Subject returns the user of a valid access token in the authorization header, empty for
+// anonymous requests
+func Subject(r *http.Request) string {
+	token := BearerToken(r)
+	if token == "" {
+		return ""
+	}
+
+	claims, err := jwt.ParseAccessToken(token)
+	if err != nil {
+		return ""
+	}
+
+	return claims.UserID
+}
+
+// DecodeJSON reads the body of the request into v. It answers the request itself and
+// returns false when the body is not valid JSON
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func WriteJSON(w http.ResponseWriter, v any) {
+	WriteJSONStatus(w, http.StatusOK, v)
+}
+
+func WriteJSONStatus(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// WriteMessage answers with the message of an action, the way the gRPC responses carry it
+func WriteMessage(w http.ResponseWriter, message string) {
+	WriteJSON(w, struct {
+		Message string `json:"message"`
+	}{message})
+}
+
+// Client collects what is known about the caller from the request. X-Forwarded-For is only
+// believed when the request comes from one of the proxies
+func Client(r *http.Request, proxies clientip.Proxies) models.Client {
+	return models.Client{
+		IP:          proxies.Resolve(r.RemoteAddr, r.Header.Values("X-Forwarded-For")),
+		UserAgent:   r.UserAgent(),
+		Fingerprint: r.Header.Get("X-Device-Fingerprint"),
+		DeviceToken: r.Header.Get("X-Device-Token"),
+	}
+}
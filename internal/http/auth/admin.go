@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"AuthService/internal/services/auth"
+	"errors"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const usersPath = "/v1/admin/users"
+
+type createUserRequest struct {
+	Username          string `json:"username"`
+	Email             string `json:"email"`
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+type createUserResponse struct {
+	UserID string `json:"user_id"`
+}
+
+func registerAdmin(mux *runtime.ServeMux, auth Auth) error {
+	return mux.HandlePath(http.MethodPost, usersPath, createUser(auth))
+}
+
+// createUser opens an account on behalf of someone, for administrators only. Without a
+// temporary password the user is sent a link to set theirs
+func createUser(authService Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req createUserRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		userId, err := authService.CreateUser(r.Context(), api.BearerToken(r), req.Username, req.Email, req.TemporaryPassword)
+		if err != nil {
+			// The credentials are the ones of the new account here, not the caller's
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				http.Error(w, "invalid username or email", http.StatusBadRequest)
+				return
+			}
+
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteJSONStatus(w, http.StatusCreated, createUserResponse{UserID: userId})
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const accountPath = "/v1/auth/account"
+
+func registerDeletion(mux *runtime.ServeMux, auth Auth) error {
+	return mux.HandlePath(http.MethodDelete, accountPath, deleteAccount(auth))
+}
+
+// deleteAccount schedules the deletion of the caller's account, it takes a stepped up token
+func deleteAccount(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		message, err := auth.DeleteAccount(r.Context(), api.BearerToken(r))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
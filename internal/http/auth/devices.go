@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const devicesPath = "/v1/auth/devices"
+
+func registerDevices(mux *runtime.ServeMux, auth Auth) error {
+	if err := mux.HandlePath(http.MethodGet, devicesPath, listTrustedDevices(auth)); err != nil {
+		return err
+	}
+
+	if err := mux.HandlePath(http.MethodDelete, devicesPath, forgetTrustedDevices(auth)); err != nil {
+		return err
+	}
+
+	return mux.HandlePath(http.MethodDelete, devicesPath+"/{id}", forgetTrustedDevices(auth))
+}
+
+func listTrustedDevices(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		claims, err := auth.Authenticate(r.Context(), api.BearerToken(r))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		devices, err := auth.ListTrustedDevices(r.Context(), claims.UserID)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteJSON(w, devices)
+	}
+}
+
+// forgetTrustedDevices forgets the device of the path, or every device of the caller without one
+func forgetTrustedDevices(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		claims, err := auth.Authenticate(r.Context(), api.BearerToken(r))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		var deviceIds []string
+		if id := params["id"]; id != "" {
+			if _, err := uuid.Parse(id); err != nil {
+				http.Error(w, "invalid device id", http.StatusBadRequest)
+				return
+			}
+
+			deviceIds = append(deviceIds, id)
+		}
+
+		message, err := auth.ForgetTrustedDevices(r.Context(), claims.UserID, deviceIds...)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
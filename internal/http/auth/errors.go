@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"AuthService/internal/lib/password"
+	"AuthService/internal/services/auth"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// policyErrorResponse lists the broken password rules, keyed like the gRPC error details
+type policyErrorResponse struct {
+	Error      string               `json:"error"`
+	Violations []password.Violation `json:"violations"`
+}
+
+// writeAPIError answers a failed API call with the status its error stands for
+func writeAPIError(w http.ResponseWriter, err error) {
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		api.WriteJSONStatus(w, http.StatusBadRequest, policyErrorResponse{
+			Error:      "password does not satisfy the policy",
+			Violations: policyErr.Violations,
+		})
+		return
+	}
+
+	var lockoutErr *auth.LockoutError
+	if errors.As(err, &lockoutErr) {
+		retryAfter := time.Until(lockoutErr.RetryAt).Round(time.Second)
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+
+		message := "too many failed attempts, try again later"
+		if lockoutErr.Locked {
+			message = "account temporarily locked after too many failed attempts"
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		http.Error(w, message, http.StatusTooManyRequests)
+		return
+	}
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+	case errors.Is(err, auth.ErrStepUpRequired):
+		http.Error(w, "step-up authentication required", http.StatusUnauthorized)
+	case errors.Is(err, auth.ErrPasswordChangeRequired):
+		http.Error(w, "password change required", http.StatusForbidden)
+	case errors.Is(err, auth.ErrPasswordExpired):
+		http.Error(w, "password expired", http.StatusForbidden)
+	case errors.Is(err, auth.ErrPolicyAcceptanceRequired):
+		http.Error(w, "policy acceptance required", http.StatusForbidden)
+	case errors.Is(err, auth.ErrLoginBlocked):
+		http.Error(w, "login blocked", http.StatusForbidden)
+	case errors.Is(err, auth.ErrPermissionDenied):
+		http.Error(w, "permission denied", http.StatusForbidden)
+	case errors.Is(err, auth.ErrInvalidStatus):
+		http.Error(w, "invalid account status", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrInvalidExportFormat):
+		http.Error(w, "invalid export format", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrExportNotFound):
+		http.Error(w, "export not found", http.StatusNotFound)
+	case errors.Is(err, auth.ErrInvalidUsername):
+		http.Error(w, "invalid username", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrUsernameTaken):
+		http.Error(w, "username already taken", http.StatusConflict)
+	case errors.Is(err, auth.ErrUsernameChangeTooSoon):
+		http.Error(w, "username changed too recently", http.StatusTooManyRequests)
+	case errors.Is(err, auth.ErrInvalidInvitation):
+		http.Error(w, "invalid invitation", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrInvitationNotFound):
+		http.Error(w, "invitation not found", http.StatusNotFound)
+	case errors.Is(err, auth.ErrUserAlreadyExists):
+		http.Error(w, "user already exists", http.StatusConflict)
+	case errors.Is(err, auth.ErrInvalidPolicy):
+		http.Error(w, "invalid policy", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrPolicyExists):
+		http.Error(w, "policy version already exists", http.StatusConflict)
+	case errors.Is(err, auth.ErrLockoutNotFound):
+		http.Error(w, "user is not locked out", http.StatusNotFound)
+	case errors.Is(err, auth.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, auth.ErrDeviceNotFound):
+		http.Error(w, "device not found", http.StatusNotFound)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"AuthService/internal/services/auth"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAPIError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantBody       string
+		wantRetryAfter string
+	}{
+		{
+			name:       "wrong password",
+			err:        fmt.Errorf("auth.StepUp: %w", auth.ErrInvalidCredentials),
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "invalid credentials",
+		},
+		{
+			name:           "too soon after a failure",
+			err:            fmt.Errorf("auth.StepUp: %w", &auth.LockoutError{RetryAt: time.Now().Add(30 * time.Second)}),
+			wantStatus:     http.StatusTooManyRequests,
+			wantBody:       "too many failed attempts, try again later",
+			wantRetryAfter: "30",
+		},
+		{
+			name:           "locked out",
+			err:            fmt.Errorf("auth.StepUp: %w", &auth.LockoutError{RetryAt: time.Now().Add(15 * time.Minute), Locked: true}),
+			wantStatus:     http.StatusTooManyRequests,
+			wantBody:       "account temporarily locked after too many failed attempts",
+			wantRetryAfter: "900",
+		},
+		{
+			name:           "lockout just over",
+			err:            fmt.Errorf("auth.StepUp: %w", &auth.LockoutError{RetryAt: time.Now()}),
+			wantStatus:     http.StatusTooManyRequests,
+			wantBody:       "too many failed attempts, try again later",
+			wantRetryAfter: "1",
+		},
+		{
+			name:       "abusive source",
+			err:        fmt.Errorf("auth.StepUp: %w", auth.ErrLoginBlocked),
+			wantStatus: http.StatusForbidden,
+			wantBody:   "login blocked",
+		},
+		{
+			name:       "unexpected error",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			writeAPIError(rec, tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			if body := strings.TrimSpace(rec.Body.String()); body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/http/api"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const (
+	exportsPath       = "/v1/auth/exports"
+	exportPath        = exportsPath + "/{id}"
+	exportArchivePath = exportPath + "/archive"
+)
+
+type exportRequest struct {
+	Format string `json:"format"`
+}
+
+type exportResponse struct {
+	ExportID string `json:"export_id"`
+}
+
+func registerExports(mux *runtime.ServeMux, auth Auth) error {
+	if err := mux.HandlePath(http.MethodPost, exportsPath, exportMyData(auth)); err != nil {
+		return err
+	}
+
+	if err := mux.HandlePath(http.MethodGet, exportPath, getDataExport(auth)); err != nil {
+		return err
+	}
+
+	return mux.HandlePath(http.MethodGet, exportArchivePath, downloadDataExport(auth))
+}
+
+// exportMyData queues an export of the caller's data, it takes a stepped up token
+func exportMyData(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req exportRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		id, err := auth.ExportMyData(r.Context(), api.BearerToken(r), req.Format)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteJSONStatus(w, http.StatusAccepted, exportResponse{ExportID: id})
+	}
+}
+
+// getDataExport reports the status of an export, the archive is downloaded on its own
+func getDataExport(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		export, ok := dataExport(w, r, auth, params)
+		if !ok {
+			return
+		}
+
+		api.WriteJSON(w, export)
+	}
+}
+
+func downloadDataExport(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		export, ok := dataExport(w, r, auth, params)
+		if !ok {
+			return
+		}
+
+		if export.Status != models.ExportReady {
+			http.Error(w, "export is not ready", http.StatusConflict)
+			return
+		}
+
+		contentType, name := "application/json", "personal-data.json"
+		if export.Format == models.ExportFormatZip {
+			contentType, name = "application/zip", "personal-data.zip"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		_, _ = w.Write(export.Archive)
+	}
+}
+
+// dataExport returns the export of the path if it belongs to the caller. It answers the
+// request itself and returns false when it can't
+func dataExport(w http.ResponseWriter, r *http.Request, auth Auth, params map[string]string) (*models.DataExport, bool) {
+	exportId := params["id"]
+	if _, err := uuid.Parse(exportId); err != nil {
+		http.Error(w, "invalid export id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	export, err := auth.GetDataExport(r.Context(), api.BearerToken(r), exportId)
+	if err != nil {
+		writeAPIError(w, err)
+		return nil, false
+	}
+
+	return export, true
+}
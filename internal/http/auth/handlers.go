@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/clientip"
+	"AuthService/internal/lib/jwt"
+	"AuthService/internal/services/auth"
+	"context"
+	"errors"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// Auth is the part of the auth service served by plain handlers: the links that users open
+// from their emails and the calls the protos have no methods for
+type Auth interface {
+	VerifyEmail(ctx context.Context, token string) (message string, err error)
+	ConfirmEmailChange(ctx context.Context, token string) (message string, err error)
+	RevertEmailChange(ctx context.Context, token string) (message string, err error)
+	ResendVerification(ctx context.Context, email string) (message string, err error)
+	RequestPasswordReset(ctx context.Context, email string) (message string, err error)
+	ResetPassword(ctx context.Context, token, newPassword string) (message string, err error)
+	DeleteAccount(ctx context.Context, accessToken string) (message string, err error)
+
+	SetAccountStatus(
+		ctx context.Context,
+		accessToken, userId, status, reason string,
+		expiresAt *time.Time,
+	) (message string, err error)
+	ClearAccountStatus(ctx context.Context, accessToken, userId, reason string) (message string, err error)
+
+	ExportMyData(ctx context.Context, accessToken, format string) (exportId string, err error)
+	GetDataExport(ctx context.Context, accessToken, exportId string) (export *models.DataExport, err error)
+
+	ChangeUsername(ctx context.Context, accessToken, username string) (message string, err error)
+
+	CreateInvitation(
+		ctx context.Context,
+		accessToken, email string,
+		maxUses int,
+		expiresAt *time.Time,
+	) (invitationId, code string, err error)
+	RevokeInvitation(ctx context.Context, accessToken, invitationId string) (message string, err error)
+
+	CreateUser(ctx context.Context, accessToken, login, email, temporaryPassword string) (userId string, err error)
+
+	PublishPolicy(
+		ctx context.Context,
+		accessToken, kind, version, documentURL string,
+		mandatory bool,
+	) (documentId string, err error)
+	PendingPolicies(ctx context.Context, accessToken string) (documents []models.PolicyDocument, err error)
+	AcceptPolicies(
+		ctx context.Context,
+		accessToken string,
+		documentIds []string,
+		client models.Client,
+	) (message string, err error)
+
+	GetLoginLockout(ctx context.Context, accessToken, userId string) (lockout *models.LoginLockout, err error)
+	ClearLoginLockout(ctx context.Context, accessToken, userId, reason string) (message string, err error)
+
+	Authenticate(ctx context.Context, accessToken string) (claims *jwt.AccessClaims, err error)
+	StepUp(ctx context.Context, accessToken, password string, client models.Client) (token string, err error)
+
+	ListTrustedDevices(ctx context.Context, userId string) (devices []models.TrustedDevice, err error)
+	ForgetTrustedDevices(ctx context.Context, userId string, deviceIds ...string) (message string, err error)
+}
+
+// link is an action behind a link from an email and the page that asks to confirm it
+type link struct {
+	action func(ctx context.Context, token string) (string, error)
+	prompt string
+	button string
+}
+
+// confirmPage asks for a click before the token is used. Mail scanners and link previews
+// fetch links on their own, a GET must not change anything
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Button}}</title></head>
+<body>
+<form method="post">
+<p>{{.Prompt}}</p>
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">{{.Button}}</button>
+</form>
+</body>
+</html>
+`))
+
+// Register adds the email link and API handlers to the gateway mux. The client address of
+// a request is taken from X-Forwarded-For only when it comes from one of the proxies
+func Register(mux *runtime.ServeMux, auth Auth, proxies clientip.Proxies) error {
+	if err := registerDevices(mux, auth); err != nil {
+		return err
+	}
+
+	if err := registerStepUp(mux, auth, proxies); err != nil {
+		return err
+	}
+
+	if err := registerVerification(mux, auth); err != nil {
+		return err
+	}
+
+	if err := registerPasswordReset(mux, auth); err != nil {
+		return err
+	}
+
+	if err := registerDeletion(mux, auth); err != nil {
+		return err
+	}
+
+	if err := registerAccountStatus(mux, auth); err != nil {
+		return err
+	}
+
+	if err := registerExports(mux, auth); err != nil {
+		return err
+	}
+
+	if err := registerUsername(mux, auth); err != nil {
+		return err
+	}
+
+	if err := registerInvitations(mux, auth); err != nil {
+		return err
+	}
+
+	if err := registerAdmin(mux, auth); err != nil {
+		return err
+	}
+
+	if err := registerPolicies(mux, auth, proxies); err != nil {
+		return err
+	}
+
+	if err := registerLoginLockout(mux, auth); err != nil {
+		return err
+	}
+
+	routes := map[string]link{
+		"/verify-email": {
+			action: auth.VerifyEmail,
+			prompt: "Confirm that this email address is yours.",
+			button: "Verify email",
+		},
+		"/confirm-email-change": {
+			action: auth.ConfirmEmailChange,
+			prompt: "Confirm the new email address of your account.",
+			button: "Confirm email change",
+		},
+		"/revert-email-change": {
+			action: auth.RevertEmailChange,
+			prompt: "Cancel the email change and sign out of every session.",
+			button: "Cancel email change",
+		},
+	}
+
+	for path, l := range routes {
+		if err := mux.HandlePath(http.MethodGet, path, l.confirm); err != nil {
+			return err
+		}
+
+		if err := mux.HandlePath(http.MethodPost, path, l.submit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// confirm serves the page a link opens, the token is carried over to the form
+func (l link) confirm(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "token is empty", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// The token is in the URL, it must not leak to anything the page links to
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	_ = confirmPage.Execute(w, struct {
+		Prompt string
+		Button string
+		Token  string
+	}{l.prompt, l.button, token})
+}
+
+// submit runs the action once the page was confirmed
+func (l link) submit(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	token := r.PostFormValue("token")
+	if token == "" {
+		http.Error(w, "token is empty", http.StatusBadRequest)
+		return
+	}
+
+	message, err := l.action(r.Context(), token)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeMessage(w, message)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, auth.ErrInvalidToken) {
+		http.Error(w, "link is invalid or expired", http.StatusBadRequest)
+		return
+	}
+
+	if errors.Is(err, auth.ErrEmailAlreadyTaken) {
+		http.Error(w, "email already taken", http.StatusConflict)
+		return
+	}
+
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+func writeMessage(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(message))
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+	"time"
+)
+
+const (
+	invitationsPath = "/v1/admin/invitations"
+	invitationPath  = invitationsPath + "/{id}"
+)
+
+type createInvitationRequest struct {
+	Email     string     `json:"email"`
+	MaxUses   int        `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type createInvitationResponse struct {
+	InvitationID string `json:"invitation_id"`
+	Code         string `json:"code"`
+}
+
+func registerInvitations(mux *runtime.ServeMux, auth Auth) error {
+	if err := mux.HandlePath(http.MethodPost, invitationsPath, createInvitation(auth)); err != nil {
+		return err
+	}
+
+	return mux.HandlePath(http.MethodDelete, invitationPath, revokeInvitation(auth))
+}
+
+// createInvitation invites someone to register, for administrators only
+func createInvitation(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req createInvitationRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		id, code, err := auth.CreateInvitation(r.Context(), api.BearerToken(r), req.Email, req.MaxUses, req.ExpiresAt)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteJSONStatus(w, http.StatusCreated, createInvitationResponse{InvitationID: id, Code: code})
+	}
+}
+
+func revokeInvitation(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		invitationId := params["id"]
+		if _, err := uuid.Parse(invitationId); err != nil {
+			http.Error(w, "invalid invitation id", http.StatusBadRequest)
+			return
+		}
+
+		message, err := auth.RevokeInvitation(r.Context(), api.BearerToken(r), invitationId)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const loginLockoutPath = "/v1/admin/users/{id}/lockout"
+
+func registerLoginLockout(mux *runtime.ServeMux, auth Auth) error {
+	if err := mux.HandlePath(http.MethodGet, loginLockoutPath, getLoginLockout(auth)); err != nil {
+		return err
+	}
+
+	return mux.HandlePath(http.MethodDelete, loginLockoutPath, clearLoginLockout(auth))
+}
+
+// getLoginLockout reports the failed logins of the user of the path, for administrators only
+func getLoginLockout(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userId, ok := userIdParam(w, params)
+		if !ok {
+			return
+		}
+
+		lockout, err := auth.GetLoginLockout(r.Context(), api.BearerToken(r), userId)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteJSON(w, lockout)
+	}
+}
+
+// clearLoginLockout lets the user of the path log in again, the reason is in the query
+func clearLoginLockout(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userId, ok := userIdParam(w, params)
+		if !ok {
+			return
+		}
+
+		message, err := auth.ClearLoginLockout(r.Context(), api.BearerToken(r), userId, r.URL.Query().Get("reason"))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"AuthService/internal/lib/clientip"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const (
+	publishPolicyPath   = "/v1/admin/policies"
+	pendingPoliciesPath = "/v1/auth/policies/pending"
+	acceptPoliciesPath  = "/v1/auth/policies/accept"
+)
+
+type publishPolicyRequest struct {
+	Kind      string `json:"kind"`
+	Version   string `json:"version"`
+	URL       string `json:"url"`
+	Mandatory bool   `json:"mandatory"`
+}
+
+type publishPolicyResponse struct {
+	DocumentID string `json:"document_id"`
+}
+
+type acceptPoliciesRequest struct {
+	DocumentIDs []string `json:"document_ids"`
+}
+
+func registerPolicies(mux *runtime.ServeMux, auth Auth, proxies clientip.Proxies) error {
+	if err := mux.HandlePath(http.MethodPost, publishPolicyPath, publishPolicy(auth)); err != nil {
+		return err
+	}
+
+	if err := mux.HandlePath(http.MethodGet, pendingPoliciesPath, pendingPolicies(auth)); err != nil {
+		return err
+	}
+
+	return mux.HandlePath(http.MethodPost, acceptPoliciesPath, acceptPolicies(auth, proxies))
+}
+
+// publishPolicy publishes a new version of a policy, for administrators only
+func publishPolicy(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req publishPolicyRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		id, err := auth.PublishPolicy(r.Context(), api.BearerToken(r), req.Kind, req.Version, req.URL, req.Mandatory)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteJSONStatus(w, http.StatusCreated, publishPolicyResponse{DocumentID: id})
+	}
+}
+
+// pendingPolicies lists the policies the caller still has to accept, a token restricted
+// to accepting them is enough
+func pendingPolicies(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		documents, err := auth.PendingPolicies(r.Context(), api.BearerToken(r))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteJSON(w, documents)
+	}
+}
+
+// acceptPolicies records the acceptance of the caller together with where it came from
+func acceptPolicies(auth Auth, proxies clientip.Proxies) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req acceptPoliciesRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		message, err := auth.AcceptPolicies(r.Context(), api.BearerToken(r), req.DocumentIDs, api.Client(r, proxies))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"AuthService/internal/services/auth"
+	"errors"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const (
+	passwordResetPath        = "/v1/auth/password-reset"
+	passwordResetConfirmPath = "/v1/auth/password-reset/confirm"
+)
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func registerPasswordReset(mux *runtime.ServeMux, auth Auth) error {
+	if err := mux.HandlePath(http.MethodPost, passwordResetPath, requestPasswordReset(auth)); err != nil {
+		return err
+	}
+
+	return mux.HandlePath(http.MethodPost, passwordResetConfirmPath, resetPassword(auth))
+}
+
+func requestPasswordReset(authService Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req emailRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		if req.Email == "" {
+			http.Error(w, "email is empty", http.StatusBadRequest)
+			return
+		}
+
+		message, err := authService.RequestPasswordReset(r.Context(), req.Email)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
+
+// resetPassword sets the new password with the token of a reset link, the page the link
+// opens posts both here
+func resetPassword(authService Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req resetPasswordRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		if req.Token == "" {
+			http.Error(w, "token is empty", http.StatusBadRequest)
+			return
+		}
+
+		if req.NewPassword == "" {
+			http.Error(w, "new password is empty", http.StatusBadRequest)
+			return
+		}
+
+		message, err := authService.ResetPassword(r.Context(), req.Token, req.NewPassword)
+		if err != nil {
+			// The token is the one of the link here, not an access token
+			if errors.Is(err, auth.ErrInvalidToken) {
+				http.Error(w, "link is invalid or expired", http.StatusBadRequest)
+				return
+			}
+
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+	"time"
+)
+
+const accountStatusPath = "/v1/admin/users/{id}/status"
+
+type setAccountStatusRequest struct {
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func registerAccountStatus(mux *runtime.ServeMux, auth Auth) error {
+	if err := mux.HandlePath(http.MethodPut, accountStatusPath, setAccountStatus(auth)); err != nil {
+		return err
+	}
+
+	return mux.HandlePath(http.MethodDelete, accountStatusPath, clearAccountStatus(auth))
+}
+
+// setAccountStatus suspends, locks or bans the user of the path, for administrators only
+func setAccountStatus(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userId, ok := userIdParam(w, params)
+		if !ok {
+			return
+		}
+
+		var req setAccountStatusRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		message, err := auth.SetAccountStatus(r.Context(), api.BearerToken(r), userId, req.Status, req.Reason, req.ExpiresAt)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
+
+// clearAccountStatus makes the account of the path active again, the reason is in the query
+func clearAccountStatus(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userId, ok := userIdParam(w, params)
+		if !ok {
+			return
+		}
+
+		message, err := auth.ClearAccountStatus(r.Context(), api.BearerToken(r), userId, r.URL.Query().Get("reason"))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
+
+// userIdParam returns the user id of the path. It answers the request itself and returns
+// false when the id is not a valid one
+func userIdParam(w http.ResponseWriter, params map[string]string) (string, bool) {
+	userId := params["id"]
+	if _, err := uuid.Parse(userId); err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return "", false
+	}
+
+	return userId, true
+}
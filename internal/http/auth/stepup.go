@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"AuthService/internal/lib/clientip"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const stepUpPath = "/v1/auth/step-up"
+
+type stepUpRequest struct {
+	Password string `json:"password"`
+}
+
+type stepUpResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+func registerStepUp(mux *runtime.ServeMux, auth Auth, proxies clientip.Proxies) error {
+	return mux.HandlePath(http.MethodPost, stepUpPath, stepUp(auth, proxies))
+}
+
+// stepUp trades the access token and the password for a token fit for sensitive operations
+func stepUp(auth Auth, proxies clientip.Proxies) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req stepUpRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		if req.Password == "" {
+			http.Error(w, "password is empty", http.StatusBadRequest)
+			return
+		}
+
+		token, err := auth.StepUp(r.Context(), api.BearerToken(r), req.Password, api.Client(r, proxies))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteJSON(w, stepUpResponse{AccessToken: token})
+	}
+}
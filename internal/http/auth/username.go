@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const usernamePath = "/v1/auth/username"
+
+type changeUsernameRequest struct {
+	Username string `json:"username"`
+}
+
+func registerUsername(mux *runtime.ServeMux, auth Auth) error {
+	return mux.HandlePath(http.MethodPut, usernamePath, changeUsername(auth))
+}
+
+// changeUsername renames the caller, it takes a stepped up token
+func changeUsername(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req changeUsernameRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		message, err := auth.ChangeUsername(r.Context(), api.BearerToken(r), req.Username)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"AuthService/internal/http/api"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+)
+
+const resendVerificationPath = "/v1/auth/verification/resend"
+
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+func registerVerification(mux *runtime.ServeMux, auth Auth) error {
+	return mux.HandlePath(http.MethodPost, resendVerificationPath, resendVerification(auth))
+}
+
+func resendVerification(auth Auth) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req emailRequest
+		if !api.DecodeJSON(w, r, &req) {
+			return
+		}
+
+		if req.Email == "" {
+			http.Error(w, "email is empty", http.StatusBadRequest)
+			return
+		}
+
+		message, err := auth.ResendVerification(r.Context(), req.Email)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		api.WriteMessage(w, message)
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"AuthService/internal/http/api"
+	"AuthService/internal/lib/clientip"
+	"AuthService/internal/lib/ratelimit"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit refuses requests over the limits with 429 Too Many Requests, telling when to retry
+// in the Retry-After header, in seconds. Routes are named by their method and path pattern,
+// such as "POST /v1/auth/step-up", callers are told apart like gRPC callers so that both share
+// their buckets. A failing limiter is logged and lets requests through
+func RateLimit(
+	log *slog.Logger,
+	limiter ratelimit.Limiter,
+	limits ratelimit.Limits,
+	proxies clientip.Proxies,
+	subject func(r *http.Request) string,
+) runtime.Middleware {
+	log = log.With(slog.String("op", "middleware.RateLimit"))
+
+	return func(next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			route := routeOf(r)
+
+			result, err := limiter.Allow(r.Context(), limits.Buckets(api.Client(r, proxies).IP, subject(r), route))
+			if err != nil {
+				log.Error("failed to check rate limit", "route", route, "error", err)
+
+				next(w, r, pathParams)
+				return
+			}
+
+			if !result.Allowed {
+				log.Info("rate limit exceeded", "key", result.Exhausted.Key, "route", route)
+
+				seconds := int(result.RetryAfter.Round(time.Second).Seconds())
+				if seconds < 1 {
+					seconds = 1
+				}
+
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				http.Error(w, "rate limit exceeded, try again later", http.StatusTooManyRequests)
+				return
+			}
+
+			next(w, r, pathParams)
+		}
+	}
+}
+
+// routeOf names the route a request was matched to, path variables written as {name}
+func routeOf(r *http.Request) string {
+	pattern, ok := runtime.HTTPPattern(r.Context())
+	if !ok {
+		return r.Method + " " + r.URL.Path
+	}
+
+	return r.Method + " " + strings.ReplaceAll(pattern.String(), "=*}", "}")
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"AuthService/internal/lib/clientip"
+	"AuthService/internal/lib/ratelimit"
+	"context"
+	"errors"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// recordingLimiter remembers the buckets it was asked for and answers with result and err
+type recordingLimiter struct {
+	keys   []string
+	result ratelimit.Result
+	err    error
+}
+
+func (l *recordingLimiter) Allow(_ context.Context, buckets []ratelimit.Bucket) (ratelimit.Result, error) {
+	l.keys = l.keys[:0]
+	for _, b := range buckets {
+		l.keys = append(l.keys, b.Key)
+	}
+
+	return l.result, l.err
+}
+
+func TestRateLimit(t *testing.T) {
+	rule := ratelimit.Rule{Limit: 1, Period: time.Minute}
+
+	limits := ratelimit.Limits{
+		IP:      rule,
+		Subject: rule,
+		Methods: map[string]ratelimit.Rule{
+			"POST /v1/auth/step-up":               rule,
+			"DELETE /v1/admin/users/{id}/lockout": rule,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		subject        string
+		result         ratelimit.Result
+		err            error
+		wantKeys       []string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:       "allowed",
+			method:     http.MethodPost,
+			path:       "/v1/auth/step-up",
+			result:     ratelimit.Result{Allowed: true},
+			wantKeys:   []string{"ip:192.0.2.1", "method:POST /v1/auth/step-up:ip:192.0.2.1"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "route with a path variable",
+			method:     http.MethodDelete,
+			path:       "/v1/admin/users/42/lockout",
+			subject:    "admin",
+			result:     ratelimit.Result{Allowed: true},
+			wantKeys:   []string{"ip:192.0.2.1", "sub:admin", "method:DELETE /v1/admin/users/{id}/lockout:sub:admin"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "route without a rule of its own",
+			method:     http.MethodGet,
+			path:       "/v1/auth/devices",
+			subject:    "user",
+			result:     ratelimit.Result{Allowed: true},
+			wantKeys:   []string{"ip:192.0.2.1", "sub:user"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "refused",
+			method:         http.MethodPost,
+			path:           "/v1/auth/step-up",
+			result:         ratelimit.Result{RetryAfter: 2400 * time.Millisecond},
+			wantKeys:       []string{"ip:192.0.2.1", "method:POST /v1/auth/step-up:ip:192.0.2.1"},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "2",
+		},
+		{
+			name:           "refused with a short wait",
+			method:         http.MethodPost,
+			path:           "/v1/auth/step-up",
+			result:         ratelimit.Result{RetryAfter: 100 * time.Millisecond},
+			wantKeys:       []string{"ip:192.0.2.1", "method:POST /v1/auth/step-up:ip:192.0.2.1"},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "1",
+		},
+		{
+			name:       "failing limiter",
+			method:     http.MethodPost,
+			path:       "/v1/auth/step-up",
+			err:        errors.New("redis is down"),
+			wantKeys:   []string{"ip:192.0.2.1", "method:POST /v1/auth/step-up:ip:192.0.2.1"},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &recordingLimiter{result: tt.result, err: tt.err}
+			subject := func(*http.Request) string { return tt.subject }
+
+			mux := runtime.NewServeMux(runtime.WithMiddlewares(RateLimit(log, limiter, limits, clientip.Proxies{}, subject)))
+
+			ok := func(w http.ResponseWriter, _ *http.Request, _ map[string]string) { w.WriteHeader(http.StatusOK) }
+			for _, route := range []struct{ method, path string }{
+				{http.MethodPost, "/v1/auth/step-up"},
+				{http.MethodDelete, "/v1/admin/users/{id}/lockout"},
+				{http.MethodGet, "/v1/auth/devices"},
+			} {
+				if err := mux.HandlePath(route.method, route.path, ok); err != nil {
+					t.Fatalf("HandlePath: %v", err)
+				}
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = "192.0.2.1:4321"
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+
+			if len(limiter.keys) != len(tt.wantKeys) {
+				t.Fatalf("buckets = %v, want %v", limiter.keys, tt.wantKeys)
+			}
+
+			for i, key := range tt.wantKeys {
+				if limiter.keys[i] != key {
+					t.Errorf("bucket %d = %q, want %q", i, limiter.keys[i], key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package abuse
+
+import (
+	"AuthService/internal/domain/models"
+	"time"
+)
+
+// Response is what is done about logins from a source that looks like it is spraying
+// passwords or stuffing credentials, weakest first
+type Response string
+
+const (
+	ResponseNone      Response = ""
+	ResponseSlowDown  Response = "slow_down"
+	ResponseChallenge Response = "challenge"
+	ResponseBlock     Response = "block"
+)
+
+var responseRanks = map[Response]int{
+	ResponseNone:      0,
+	ResponseSlowDown:  1,
+	ResponseChallenge: 2,
+	ResponseBlock:     3,
+}
+
+func IsValidResponse(response string) bool {
+	_, ok := responseRanks[Response(response)]
+
+	return ok && response != ""
+}
+
+// Detectors of abuse from a single source
+const (
+	DetectorIPFailures        = "ip_failures"
+	DetectorNetworkFailures   = "network_failures"
+	DetectorDistinctUsernames = "distinct_usernames"
+)
+
+// Rule applies its response once a count reaches the threshold. A threshold of 0 turns it off
+type Rule struct {
+	Threshold int
+	Response  Response
+}
+
+// Policy looks at the failed logins of the last Window across every account: per address,
+// per network and the number of usernames tried from an address
+type Policy struct {
+	Enabled           bool
+	Window            time.Duration
+	IPFailures        Rule
+	NetworkFailures   Rule
+	DistinctUsernames Rule
+	SlowDownDelay     time.Duration
+}
+
+// Detection is a rule whose threshold was reached
+type Detection struct {
+	Detector string
+	Count    int
+	Response Response
+}
+
+// Evaluate returns the strongest response the stats call for and the detections behind it
+func (p Policy) Evaluate(stats models.LoginFailureStats) (Response, []Detection) {
+	if !p.Enabled {
+		return ResponseNone, nil
+	}
+
+	response := ResponseNone
+	detections := p.detect(stats, models.LoginFailureStats{}, func(count, _, threshold int) bool {
+		return count >= threshold
+	})
+
+	for _, d := range detections {
+		if responseRanks[d.Response] > responseRanks[response] {
+			response = d.Response
+		}
+	}
+
+	return response, detections
+}
+
+// Crossed returns the rules a failure took over their threshold, given the stats before and
+// after it. A count that stays at or past the threshold, such as usernames tried again, is
+// not reported again
+func (p Policy) Crossed(before, after models.LoginFailureStats) []Detection {
+	if !p.Enabled {
+		return nil
+	}
+
+	return p.detect(after, before, func(count, previous, threshold int) bool {
+		return previous < threshold && count >= threshold
+	})
+}
+
+func (p Policy) detect(
+	stats, previous models.LoginFailureStats,
+	match func(count, previous, threshold int) bool,
+) []Detection {
+	var detections []Detection
+
+	for _, c := range []struct {
+		detector string
+		count    int
+		previous int
+		rule     Rule
+	}{
+		{DetectorIPFailures, stats.IPFailures, previous.IPFailures, p.IPFailures},
+		{DetectorNetworkFailures, stats.NetworkFailures, previous.NetworkFailures, p.NetworkFailures},
+		{DetectorDistinctUsernames, stats.DistinctUsernames, previous.DistinctUsernames, p.DistinctUsernames},
+	} {
+		if c.rule.Threshold > 0 && match(c.count, c.previous, c.rule.Threshold) {
+			detections = append(detections, Detection{Detector: c.detector, Count: c.count, Response: c.rule.Response})
+		}
+	}
+
+	return detections
+}
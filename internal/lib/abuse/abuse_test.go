@@ -0,0 +1,153 @@
+package abuse
+
+import (
+	"AuthService/internal/domain/models"
+	"testing"
+	"time"
+)
+
+func testPolicy() Policy {
+	return Policy{
+		Enabled:           true,
+		Window:            15 * time.Minute,
+		IPFailures:        Rule{Threshold: 20, Response: ResponseSlowDown},
+		NetworkFailures:   Rule{Threshold: 100, Response: ResponseChallenge},
+		DistinctUsernames: Rule{Threshold: 10, Response: ResponseBlock},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     Policy
+		stats      models.LoginFailureStats
+		want       Response
+		detections int
+	}{
+		{
+			name:   "quiet source",
+			policy: testPolicy(),
+			stats:  models.LoginFailureStats{IPFailures: 3, NetworkFailures: 3, DistinctUsernames: 1},
+			want:   ResponseNone,
+		},
+		{
+			name:       "many failures from an address",
+			policy:     testPolicy(),
+			stats:      models.LoginFailureStats{IPFailures: 20, NetworkFailures: 20, DistinctUsernames: 1},
+			want:       ResponseSlowDown,
+			detections: 1,
+		},
+		{
+			name:       "strongest response wins",
+			policy:     testPolicy(),
+			stats:      models.LoginFailureStats{IPFailures: 50, NetworkFailures: 120, DistinctUsernames: 12},
+			want:       ResponseBlock,
+			detections: 3,
+		},
+		{
+			name:       "busy network",
+			policy:     testPolicy(),
+			stats:      models.LoginFailureStats{IPFailures: 2, NetworkFailures: 100, DistinctUsernames: 2},
+			want:       ResponseChallenge,
+			detections: 1,
+		},
+		{
+			name: "disabled rule",
+			policy: func() Policy {
+				p := testPolicy()
+				p.DistinctUsernames.Threshold = 0
+				return p
+			}(),
+			stats: models.LoginFailureStats{DistinctUsernames: 500},
+			want:  ResponseNone,
+		},
+		{
+			name: "disabled policy",
+			policy: func() Policy {
+				p := testPolicy()
+				p.Enabled = false
+				return p
+			}(),
+			stats: models.LoginFailureStats{IPFailures: 500, NetworkFailures: 500, DistinctUsernames: 500},
+			want:  ResponseNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, detections := tt.policy.Evaluate(tt.stats)
+			if got != tt.want {
+				t.Errorf("Evaluate() response = %q, want %q", got, tt.want)
+			}
+			if len(detections) != tt.detections {
+				t.Errorf("Evaluate() detections = %d, want %d", len(detections), tt.detections)
+			}
+		})
+	}
+}
+
+func TestCrossed(t *testing.T) {
+	tests := []struct {
+		name   string
+		before models.LoginFailureStats
+		after  models.LoginFailureStats
+		want   []string
+	}{
+		{
+			name:   "below the thresholds",
+			before: models.LoginFailureStats{IPFailures: 5, NetworkFailures: 5, DistinctUsernames: 5},
+			after:  models.LoginFailureStats{IPFailures: 6, NetworkFailures: 6, DistinctUsernames: 6},
+		},
+		{
+			name:   "reaching a threshold",
+			before: models.LoginFailureStats{IPFailures: 19, NetworkFailures: 19, DistinctUsernames: 3},
+			after:  models.LoginFailureStats{IPFailures: 20, NetworkFailures: 20, DistinctUsernames: 3},
+			want:   []string{DetectorIPFailures},
+		},
+		{
+			name:   "jumping past a threshold",
+			before: models.LoginFailureStats{IPFailures: 18, NetworkFailures: 18, DistinctUsernames: 9},
+			after:  models.LoginFailureStats{IPFailures: 22, NetworkFailures: 22, DistinctUsernames: 11},
+			want:   []string{DetectorIPFailures, DetectorDistinctUsernames},
+		},
+		{
+			name:   "usernames tried again stay at the threshold",
+			before: models.LoginFailureStats{IPFailures: 10, NetworkFailures: 10, DistinctUsernames: 10},
+			after:  models.LoginFailureStats{IPFailures: 11, NetworkFailures: 11, DistinctUsernames: 10},
+		},
+		{
+			name:   "already past the thresholds",
+			before: models.LoginFailureStats{IPFailures: 30, NetworkFailures: 130, DistinctUsernames: 15},
+			after:  models.LoginFailureStats{IPFailures: 31, NetworkFailures: 131, DistinctUsernames: 16},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detections := testPolicy().Crossed(tt.before, tt.after)
+			if len(detections) != len(tt.want) {
+				t.Fatalf("Crossed() = %v, want detectors %v", detections, tt.want)
+			}
+
+			for i, d := range detections {
+				if d.Detector != tt.want[i] {
+					t.Errorf("Crossed()[%d] = %q, want %q", i, d.Detector, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIsValidResponse(t *testing.T) {
+	for response, want := range map[string]bool{
+		"slow_down": true,
+		"challenge": true,
+		"block":     true,
+		"":          false,
+		"ban":       false,
+	} {
+		if got := IsValidResponse(response); got != want {
+			t.Errorf("IsValidResponse(%q) = %v, want %v", response, got, want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package breach
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// bloomMagic starts every filter file, followed by the number of bits and of hash functions
+var bloomMagic = [8]byte{'P', 'W', 'B', 'L', 'O', 'O', 'M', '1'}
+
+// Bloom is a compact, in-memory stand-in for the corpus. It never misses a breached
+// password but reports a small share of other passwords as breached too
+type Bloom struct {
+	bits []byte
+	m    uint64
+	k    uint32
+}
+
+// NewBloom sizes an empty filter for n hashes at the given false positive rate
+func NewBloom(n uint64, falsePositiveRate float64) *Bloom {
+	if n == 0 {
+		n = 1
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	m = (m + 7) / 8 * 8
+
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+
+	return &Bloom{bits: make([]byte, m/8), m: m, k: k}
+}
+
+// LoadBloom reads a filter written by WriteTo
+func LoadBloom(path string) (*Bloom, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+
+	var header struct {
+		Magic [8]byte
+		M     uint64
+		K     uint32
+	}
+
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("read bloom filter header: %w", err)
+	}
+
+	if header.Magic != bloomMagic || header.M == 0 || header.M%8 != 0 || header.K == 0 {
+		return nil, fmt.Errorf("%s is not a bloom filter file", path)
+	}
+
+	b := &Bloom{bits: make([]byte, header.M/8), m: header.M, k: header.K}
+	if _, err := io.ReadFull(r, b.bits); err != nil {
+		return nil, fmt.Errorf("read bloom filter: %w", err)
+	}
+
+	return b, nil
+}
+
+// Add puts a hex SHA-1 hash of the corpus into the filter
+func (b *Bloom) Add(hash string) error {
+	digest, err := hex.DecodeString(hash)
+	if err != nil || len(digest) != 20 {
+		return fmt.Errorf("malformed sha1 %q", hash)
+	}
+
+	for _, i := range b.indexes(digest) {
+		b.bits[i/8] |= 1 << (i % 8)
+	}
+
+	return nil
+}
+
+func (b *Bloom) IsBreached(password string) (bool, error) {
+	digest, _ := hex.DecodeString(Hash(password))
+
+	for _, i := range b.indexes(digest) {
+		if b.bits[i/8]&(1<<(i%8)) == 0 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// WriteTo writes the filter in the format LoadBloom reads
+func (b *Bloom) WriteTo(w io.Writer) (int64, error) {
+	header := struct {
+		Magic [8]byte
+		M     uint64
+		K     uint32
+	}{bloomMagic, b.m, b.k}
+
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(b.bits)
+
+	return int64(binary.Size(header) + n), err
+}
+
+// indexes derives the k bit positions from the digest, which is already uniformly
+// distributed, by double hashing its first two 64 bit words
+func (b *Bloom) indexes(digest []byte) []uint64 {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+
+	indexes := make([]uint64, b.k)
+	for i := range indexes {
+		indexes[i] = (h1 + uint64(i)*h2) % b.m
+	}
+
+	return indexes
+}
@@ -0,0 +1,170 @@
+package breach
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBloom(t *testing.T) {
+	breached := []string{"password", "123456", "qwerty", "letmein", "Пароль", ""}
+
+	b := NewBloom(uint64(len(breached)), 0.001)
+	for _, password := range breached {
+		if err := b.Add(Hash(password)); err != nil {
+			t.Fatalf("Add(%q): %v", password, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{name: "breached", password: "password", want: true},
+		{name: "breached digits", password: "123456", want: true},
+		{name: "breached unicode", password: "Пароль", want: true},
+		{name: "breached empty", password: "", want: true},
+		{name: "case matters", password: "Password", want: false},
+		{name: "not breached", password: "x7#Kq9!mZ2@vR5%t", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.IsBreached(tt.password)
+			if err != nil {
+				t.Fatalf("IsBreached(%q): %v", tt.password, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("IsBreached(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBloomAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr bool
+	}{
+		{name: "upper case", hash: Hash("password")},
+		{name: "lower case", hash: "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8"},
+		{name: "not hex", hash: "ZZAA61E4C9B93F3F0682250B6CF8331B7EE68FD8", wantErr: true},
+		{name: "too short", hash: "5BAA61E4", wantErr: true},
+		{name: "sha256", hash: "5E884898DA28047151D0E56F8DC6292773603D0D6AABBDD62A11EF721D1542D8", wantErr: true},
+		{name: "empty", hash: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewBloom(10, 0.01).Add(tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Add(%q) = %v, want error %v", tt.hash, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBloomFalsePositiveRate(t *testing.T) {
+	const n = 2000
+
+	tests := []struct {
+		name string
+		rate float64
+	}{
+		{name: "one percent", rate: 0.01},
+		{name: "one per mille", rate: 0.001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBloom(n, tt.rate)
+			for i := 0; i < n; i++ {
+				if err := b.Add(Hash(fmt.Sprintf("breached-%d", i))); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+			}
+
+			positives := 0
+			for i := 0; i < 20*n; i++ {
+				if ok, _ := b.IsBreached(fmt.Sprintf("other-%d", i)); ok {
+					positives++
+				}
+			}
+
+			// Leave room for chance, the filter is sized for the rate, not bounded by it
+			if got := float64(positives) / (20 * n); got > 3*tt.rate {
+				t.Errorf("false positive rate %.4f, sized for %.4f", got, tt.rate)
+			}
+		})
+	}
+}
+
+func TestBloomRoundTrip(t *testing.T) {
+	b := NewBloom(100, 0.01)
+	if err := b.Add(Hash("password")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	var buf bytes.Buffer
+	n, err := b.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo reported %d bytes, wrote %d", n, buf.Len())
+	}
+
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBloom(path)
+	if err != nil {
+		t.Fatalf("LoadBloom: %v", err)
+	}
+
+	if loaded.m != b.m || loaded.k != b.k || !bytes.Equal(loaded.bits, b.bits) {
+		t.Errorf("loaded filter differs from the written one")
+	}
+
+	if ok, _ := loaded.IsBreached("password"); !ok {
+		t.Errorf("loaded filter misses a breached password")
+	}
+}
+
+func TestLoadBloomRejectsOtherFiles(t *testing.T) {
+	var valid bytes.Buffer
+	if _, err := NewBloom(100, 0.01).WriteTo(&valid); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "empty", content: nil},
+		{name: "wrong magic", content: append([]byte("NOTBLOOM"), valid.Bytes()[8:]...)},
+		{name: "truncated header", content: valid.Bytes()[:10]},
+		{name: "truncated bits", content: valid.Bytes()[:valid.Len()-1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "filter")
+			if err := os.WriteFile(path, tt.content, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := LoadBloom(path); err == nil {
+				t.Errorf("LoadBloom accepted a %s file", tt.name)
+			}
+		})
+	}
+}
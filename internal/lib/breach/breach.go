@@ -0,0 +1,111 @@
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Checker tells whether a password appears in a breach corpus
+type Checker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// RangeDir checks passwords against a local copy of the HIBP pwned passwords, as written by
+// the official downloader: one file per 5 hex digit SHA-1 prefix, named after it, holding
+// "SUFFIX:COUNT" lines. Passwords seen fewer than MinCount times are let through
+type RangeDir struct {
+	Dir      string
+	MinCount int
+}
+
+func NewRangeDir(dir string, minCount int) (*RangeDir, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return &RangeDir{Dir: dir, MinCount: minCount}, nil
+}
+
+func (r *RangeDir) IsBreached(password string) (bool, error) {
+	hash := Hash(password)
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := r.open(prefix)
+	if err != nil {
+		return false, err
+	}
+
+	// No file for the prefix means no breached password starts with it
+	if file == nil {
+		return false, nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, count, err := ParseLine(scanner.Text())
+		if err != nil {
+			continue
+		}
+
+		if lineSuffix == suffix {
+			return count >= r.MinCount, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+func (r *RangeDir) open(prefix string) (*os.File, error) {
+	for _, name := range []string{prefix + ".txt", prefix} {
+		file, err := os.Open(filepath.Join(r.Dir, name))
+		if err == nil {
+			return file, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Hash returns the upper case hex SHA-1 of a password, the form used by the corpus
+func Hash(password string) string {
+	sum := sha1.Sum([]byte(password))
+
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// ParseLine splits a "HASH:COUNT" line of the corpus. A line without a count counts once
+func ParseLine(line string) (hash string, count int, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", 0, fmt.Errorf("empty line")
+	}
+
+	hash, rawCount, found := strings.Cut(line, ":")
+	if !found {
+		return strings.ToUpper(hash), 1, nil
+	}
+
+	count, err = strconv.Atoi(rawCount)
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed count %q", rawCount)
+	}
+
+	return strings.ToUpper(hash), count, nil
+}
@@ -0,0 +1,86 @@
+package breach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantHash  string
+		wantCount int
+		wantErr   bool
+	}{
+		{name: "with count", line: "1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493", wantHash: "1E4C9B93F3F0682250B6CF8331B7EE68FD8", wantCount: 3861493},
+		{name: "without count", line: "1E4C9B93F3F0682250B6CF8331B7EE68FD8", wantHash: "1E4C9B93F3F0682250B6CF8331B7EE68FD8", wantCount: 1},
+		{name: "lower case", line: "1e4c9b93f3f0682250b6cf8331b7ee68fd8:2", wantHash: "1E4C9B93F3F0682250B6CF8331B7EE68FD8", wantCount: 2},
+		{name: "windows line ending", line: "1E4C9B93F3F0682250B6CF8331B7EE68FD8:7\r", wantHash: "1E4C9B93F3F0682250B6CF8331B7EE68FD8", wantCount: 7},
+		{name: "empty", line: "  ", wantErr: true},
+		{name: "malformed count", line: "1E4C9B93F3F0682250B6CF8331B7EE68FD8:many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, count, err := ParseLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLine(%q) error = %v, want error %v", tt.line, err, tt.wantErr)
+			}
+
+			if hash != tt.wantHash || count != tt.wantCount {
+				t.Errorf("ParseLine(%q) = %q, %d, want %q, %d", tt.line, hash, count, tt.wantHash, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestRangeDir(t *testing.T) {
+	dir := t.TempDir()
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	// of "letmein" B7A875FC1EA228B9061041B7CEC4BD3C52AB3CE3
+	// and of "Password" 8BE3C943B1609FFFBFC51AAD666D0A04ADF83C9D
+	files := map[string]string{
+		"5BAA6.txt": "0018A45C4D1DEF81644B54AB7F969B88D65:1\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n",
+		"B7A87":     "5FC1EA228B9061041B7CEC4BD3C52AB3CE3:2\n",
+		"8BE3C.txt": "00000000000000000000000000000000000:5\nnot a line\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		minCount int
+		password string
+		want     bool
+	}{
+		{name: "breached", minCount: 1, password: "password", want: true},
+		{name: "file without extension", minCount: 1, password: "letmein", want: true},
+		{name: "seen too rarely", minCount: 10, password: "letmein", want: false},
+		{name: "prefix without file", minCount: 1, password: "x7#Kq9!mZ2@vR5%t", want: false},
+		{name: "prefix file without the suffix", minCount: 1, password: "Password", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRangeDir(dir, tt.minCount)
+			if err != nil {
+				t.Fatalf("NewRangeDir: %v", err)
+			}
+
+			got, err := r.IsBreached(tt.password)
+			if err != nil {
+				t.Fatalf("IsBreached(%q): %v", tt.password, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("IsBreached(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Proxies are the networks of the proxies in front of the service, such as the HTTP gateway.
+// Only they are believed about the address of the client they forward a request for
+type Proxies []netip.Prefix
+
+// ParseProxies parses a list of networks in CIDR notation, a bare address stands for itself
+func ParseProxies(cidrs []string) (Proxies, error) {
+	proxies := make(Proxies, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+			}
+
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+// Trusted reports whether the address belongs to a trusted proxy
+func (p Proxies) Trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve returns the address of the client given the address of the direct peer and the
+// x-forwarded-for values of the request. The forwarded addresses are only read when the
+// peer is a trusted proxy, then the right-most one that is not a trusted proxy is the client:
+// everything left of it was written by the client itself and can be anything
+func (p Proxies) Resolve(peer string, forwardedFor []string) string {
+	ip := Host(peer)
+	if !p.Trusted(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, value := range forwardedFor {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := Host(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// A malformed hop was not written by a trusted proxy, stop at the last good one
+			return ip
+		}
+
+		if !p.Trusted(hop) {
+			return hop
+		}
+
+		ip = hop
+	}
+
+	return ip
+}
+
+// Host strips the port from an address, if there is one
+func Host(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return strings.Trim(addr, "[]")
+}
@@ -0,0 +1,82 @@
+package clientip
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	proxies, err := ParseProxies([]string{"127.0.0.1/32", "::1", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("ParseProxies: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		peer         string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name: "direct caller",
+			peer: "203.0.113.7:51000",
+			want: "203.0.113.7",
+		},
+		{
+			name:         "direct caller claiming another address",
+			peer:         "203.0.113.7:51000",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "through the gateway",
+			peer:         "127.0.0.1:40000",
+			forwardedFor: []string{"203.0.113.7"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "client spoofing through the gateway",
+			peer:         "127.0.0.1:40000",
+			forwardedFor: []string{"198.51.100.1, 192.0.2.9, 203.0.113.7"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "through a chain of trusted proxies",
+			peer:         "[::1]:40000",
+			forwardedFor: []string{"198.51.100.1, 203.0.113.7", "10.1.2.3"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "malformed hop",
+			peer:         "127.0.0.1:40000",
+			forwardedFor: []string{"203.0.113.7, not-an-ip"},
+			want:         "127.0.0.1",
+		},
+		{
+			name:         "only trusted proxies",
+			peer:         "127.0.0.1:40000",
+			forwardedFor: []string{"10.0.0.1"},
+			want:         "10.0.0.1",
+		},
+		{
+			name: "gateway without forwarded address",
+			peer: "127.0.0.1:40000",
+			want: "127.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := proxies.Resolve(tt.peer, tt.forwardedFor); got != tt.want {
+				t.Errorf("Resolve(%q, %q) = %q, want %q", tt.peer, tt.forwardedFor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProxiesRejectsGarbage(t *testing.T) {
+	if _, err := ParseProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("ParseProxies accepted an invalid prefix")
+	}
+
+	if _, err := ParseProxies([]string{"gateway"}); err == nil {
+		t.Error("ParseProxies accepted a host name")
+	}
+}
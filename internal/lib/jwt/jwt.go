@@ -4,17 +4,48 @@ import (
 	"AuthService/internal/domain/models"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"os"
 	"time"
 )
 
-func NewToken(user *models.User, tokenTTL time.Duration) (string, error) {
+const deviceTokenType = "device"
+
+// Purposes of the tokens sent to users by email
+const (
+	PurposeEmailVerification = "email_verification"
+)
+
+// AccessClaims are the claims of an access token the service relies on
+type AccessClaims struct {
+	UserID         string
+	Email          string
+	SessionID      string
+	Authentication models.Authentication
+	Restriction    string
+}
+
+// NewToken signs an access token. A token with a restriction is only good for lifting it
+func NewToken(
+	user *models.User,
+	tokenTTL time.Duration,
+	sessionID uuid.UUID,
+	authn models.Authentication,
+	restriction string,
+) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
 	claims["id"] = user.ID
 	claims["email"] = user.Email
+	claims["sid"] = sessionID
 	claims["exp"] = time.Now().Add(tokenTTL).Unix()
+	claims["acr"] = authn.Level
+	claims["amr"] = authn.Methods
+	claims["auth_time"] = authn.Time.Unix()
+	if restriction != "" {
+		claims["rst"] = restriction
+	}
 
 	if os.Getenv("JWT_SECRET") == "" {
 		return "", fmt.Errorf("jwt secret is empty")
@@ -41,6 +72,144 @@ func VerifyToken(tokenString string) (*jwt.Token, error) {
 	return token, nil
 }
 
+// ParseAccessToken verifies an access token and returns its claims
+func ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	token, err := VerifyToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	if typ, _ := claims["typ"].(string); typ != "" {
+		return nil, fmt.Errorf("not an access token")
+	}
+
+	userID, ok := claims["id"].(string)
+	if !ok || userID == "" {
+		return nil, fmt.Errorf("invalid user_id in token claims")
+	}
+
+	result := &AccessClaims{UserID: userID}
+	result.Email, _ = claims["email"].(string)
+	result.SessionID, _ = claims["sid"].(string)
+	result.Authentication.Level, _ = claims["acr"].(string)
+	result.Restriction, _ = claims["rst"].(string)
+
+	if amr, ok := claims["amr"].([]interface{}); ok {
+		for _, method := range amr {
+			if m, ok := method.(string); ok {
+				result.Authentication.Methods = append(result.Authentication.Methods, m)
+			}
+		}
+	}
+
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		result.Authentication.Time = time.Unix(int64(authTime), 0)
+	}
+
+	return result, nil
+}
+
+// NewDeviceToken signs a long-lived token that binds a trusted device to a user and a fingerprint
+func NewDeviceToken(userID, deviceID uuid.UUID, fingerprintHash string, ttl time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["typ"] = deviceTokenType
+	claims["id"] = userID
+	claims["did"] = deviceID
+	claims["fph"] = fingerprintHash
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	if os.Getenv("JWT_SECRET") == "" {
+		return "", fmt.Errorf("jwt secret is empty")
+	}
+
+	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// ParseDeviceToken verifies a device token and returns the user, device and fingerprint hash it is bound to
+func ParseDeviceToken(tokenString string) (userID, deviceID, fingerprintHash string, err error) {
+	token, err := VerifyToken(tokenString)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return "", "", "", fmt.Errorf("invalid token")
+	}
+
+	if typ, _ := claims["typ"].(string); typ != deviceTokenType {
+		return "", "", "", fmt.Errorf("not a device token")
+	}
+
+	userID, _ = claims["id"].(string)
+	deviceID, _ = claims["did"].(string)
+	fingerprintHash, _ = claims["fph"].(string)
+	if userID == "" || deviceID == "" || fingerprintHash == "" {
+		return "", "", "", fmt.Errorf("invalid device token claims")
+	}
+
+	return userID, deviceID, fingerprintHash, nil
+}
+
+// NewEmailToken signs a token for a link sent to the given email, bound to the user and the purpose of the link
+func NewEmailToken(purpose string, userID uuid.UUID, email string, ttl time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["typ"] = purpose
+	claims["id"] = userID
+	claims["email"] = email
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	if os.Getenv("JWT_SECRET") == "" {
+		return "", fmt.Errorf("jwt secret is empty")
+	}
+
+	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// ParseEmailToken verifies a token sent by email for the given purpose and returns the user and email it is bound to
+func ParseEmailToken(tokenString, purpose string) (userID, email string, err error) {
+	token, err := VerifyToken(tokenString)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return "", "", fmt.Errorf("invalid token")
+	}
+
+	if typ, _ := claims["typ"].(string); typ != purpose {
+		return "", "", fmt.Errorf("unexpected token purpose")
+	}
+
+	userID, _ = claims["id"].(string)
+	email, _ = claims["email"].(string)
+	if userID == "" || email == "" {
+		return "", "", fmt.Errorf("invalid email token claims")
+	}
+
+	return userID, email, nil
+}
+
 func GetUserIDFromToken(token *jwt.Token) (string, error) {
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
@@ -0,0 +1,106 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const argon2idID = "argon2id"
+
+// Argon2id hashes passwords with argon2id and encodes them as PHC strings:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2id struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2Hash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (a Argon2id) Hash(password []byte) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey(password, salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idID,
+		argon2.Version,
+		a.Memory,
+		a.Iterations,
+		a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a Argon2id) Verify(password []byte, encoded string) (bool, error) {
+	h, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey(password, h.salt, h.iterations, h.memory, h.parallelism, uint32(len(h.key)))
+
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+func (a Argon2id) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+argon2idID+"$")
+}
+
+func (a Argon2id) Current(encoded string) bool {
+	h, err := decodeArgon2(encoded)
+	if err != nil {
+		return false
+	}
+
+	return h.memory == a.Memory &&
+		h.iterations == a.Iterations &&
+		h.parallelism == a.Parallelism &&
+		uint32(len(h.salt)) == a.SaltLength &&
+		uint32(len(h.key)) == a.KeyLength
+}
+
+func decodeArgon2(encoded string) (*argon2Hash, error) {
+	fields := phcFields(encoded)
+	if len(fields) != 5 || fields[0] != argon2idID {
+		return nil, fmt.Errorf("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(fields[1], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version")
+	}
+
+	var h argon2Hash
+	if _, err := fmt.Sscanf(fields[2], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.parallelism); err != nil {
+		return nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(fields[3]); err != nil {
+		return nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+
+	if h.key, err = base64.RawStdEncoding.DecodeString(fields[4]); err != nil {
+		return nil, fmt.Errorf("malformed argon2id hash: %w", err)
+	}
+
+	return &h, nil
+}
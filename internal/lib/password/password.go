@@ -0,0 +1,111 @@
+package password
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrUnknownScheme = errors.New("unknown password hash scheme")
+
+// Scheme is a password hashing algorithm with its parameters
+type Scheme interface {
+	// Hash returns the encoded hash of a password
+	Hash(password []byte) (string, error)
+	// Verify reports whether the password matches an encoded hash of this scheme
+	Verify(password []byte, encoded string) (bool, error)
+	// Recognizes reports whether the encoded hash was made by this algorithm
+	Recognizes(encoded string) bool
+	// Current reports whether the encoded hash uses the parameters the scheme is configured with
+	Current(encoded string) bool
+}
+
+// Hasher hashes new passwords with its current scheme and verifies hashes of every scheme it knows,
+// so that stored hashes can be moved to the current scheme as users log in
+type Hasher struct {
+	current Scheme
+	schemes []Scheme
+	pepper  *Pepper
+}
+
+func NewHasher(current Scheme, legacy ...Scheme) *Hasher {
+	return &Hasher{
+		current: current,
+		schemes: append([]Scheme{current}, legacy...),
+	}
+}
+
+// WithPepper peppers passwords before hashing them, the pepper version is kept with the hash
+func (h *Hasher) WithPepper(pepper *Pepper) *Hasher {
+	h.pepper = pepper
+
+	return h
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	version := h.pepperVersion()
+	if version == "" {
+		return h.current.Hash([]byte(password))
+	}
+
+	peppered, err := h.pepper.apply(version, password)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := h.current.Hash(peppered)
+	if err != nil {
+		return "", err
+	}
+
+	return pepperPrefix + version + encoded, nil
+}
+
+// Verify checks a password against an encoded hash. needsRehash tells that the password
+// matched but the hash should be replaced by one of the current scheme and pepper
+func (h *Hasher) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	version, inner := splitPepper(encoded)
+
+	input := []byte(password)
+	if version != "" {
+		if h.pepper == nil {
+			return false, false, ErrUnknownPepper
+		}
+
+		if input, err = h.pepper.apply(version, password); err != nil {
+			return false, false, err
+		}
+	}
+
+	for _, scheme := range h.schemes {
+		if !scheme.Recognizes(inner) {
+			continue
+		}
+
+		ok, err := scheme.Verify(input, inner)
+		if err != nil || !ok {
+			return false, false, err
+		}
+
+		return true, scheme != h.current || !scheme.Current(inner) || version != h.pepperVersion(), nil
+	}
+
+	return false, false, ErrUnknownScheme
+}
+
+// pepperVersion returns the version new hashes are peppered with, empty without a pepper
+func (h *Hasher) pepperVersion() string {
+	if h.pepper == nil {
+		return ""
+	}
+
+	return h.pepper.current
+}
+
+// phcFields splits a PHC string "$id$v=..$params$salt$hash" into its fields without the leading empty one
+func phcFields(encoded string) []string {
+	if !strings.HasPrefix(encoded, "$") {
+		return nil
+	}
+
+	return strings.Split(encoded[1:], "$")
+}
@@ -0,0 +1,282 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Parameters far below production ones, the tests only need the encoding to be right
+var (
+	testArgon2  = Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	testArgon2B = Argon2id{Memory: 128, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	testBcrypt  = Bcrypt{Cost: 4}
+)
+
+func testPepper(t *testing.T, current string, versions ...string) *Pepper {
+	t.Helper()
+
+	keys := make(map[string][]byte, len(versions))
+	for _, version := range versions {
+		keys[version] = []byte(strings.Repeat(version, 16))
+	}
+
+	pepper, err := NewPepper(current, keys)
+	if err != nil {
+		t.Fatalf("NewPepper: %v", err)
+	}
+
+	return pepper
+}
+
+func TestHasher(t *testing.T) {
+	const secret = "correct horse battery staple"
+
+	tests := []struct {
+		name       string
+		store      *Hasher
+		verify     *Hasher
+		password   string
+		wantOK     bool
+		wantRehash bool
+		wantErr    error
+	}{
+		{
+			name:     "argon2id",
+			store:    NewHasher(testArgon2),
+			verify:   NewHasher(testArgon2),
+			password: secret,
+			wantOK:   true,
+		},
+		{
+			name:     "argon2id wrong password",
+			store:    NewHasher(testArgon2),
+			verify:   NewHasher(testArgon2),
+			password: "wrong",
+		},
+		{
+			name:       "bcrypt is upgraded",
+			store:      NewHasher(testBcrypt),
+			verify:     NewHasher(testArgon2, testBcrypt),
+			password:   secret,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "bcrypt wrong password",
+			store:    NewHasher(testBcrypt),
+			verify:   NewHasher(testArgon2, testBcrypt),
+			password: "wrong",
+		},
+		{
+			name:       "argon2id with old parameters",
+			store:      NewHasher(testArgon2),
+			verify:     NewHasher(testArgon2B),
+			password:   secret,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "scheme no longer known",
+			store:    NewHasher(testBcrypt),
+			verify:   NewHasher(testArgon2),
+			password: secret,
+			wantErr:  ErrUnknownScheme,
+		},
+		{
+			name:     "peppered",
+			store:    NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")),
+			verify:   NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")),
+			password: secret,
+			wantOK:   true,
+		},
+		{
+			name:     "peppered wrong password",
+			store:    NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")),
+			verify:   NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")),
+			password: "wrong",
+		},
+		{
+			name:       "pepper added",
+			store:      NewHasher(testArgon2),
+			verify:     NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")),
+			password:   secret,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "pepper rotated",
+			store:      NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")),
+			verify:     NewHasher(testArgon2).WithPepper(testPepper(t, "v2", "v1", "v2")),
+			password:   secret,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "pepper retired",
+			store:      NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")),
+			verify:     NewHasher(testArgon2).WithPepper(testPepper(t, "", "v1")),
+			password:   secret,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "peppered bcrypt is upgraded",
+			store:      NewHasher(testBcrypt).WithPepper(testPepper(t, "v1", "v1")),
+			verify:     NewHasher(testArgon2, testBcrypt).WithPepper(testPepper(t, "v1", "v1")),
+			password:   secret,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "pepper version dropped",
+			store:    NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")),
+			verify:   NewHasher(testArgon2).WithPepper(testPepper(t, "v2", "v2")),
+			password: secret,
+			wantErr:  ErrUnknownPepper,
+		},
+		{
+			name:     "pepper missing",
+			store:    NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")),
+			verify:   NewHasher(testArgon2),
+			password: secret,
+			wantErr:  ErrUnknownPepper,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.store.Hash(secret)
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+
+			ok, rehash, err := tt.verify.Verify(tt.password, encoded)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify(%q) error = %v, want %v", encoded, err, tt.wantErr)
+			}
+
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Errorf("Verify(%q) = %v, %v, want %v, %v", encoded, ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestHasherEncoding(t *testing.T) {
+	tests := []struct {
+		name       string
+		hasher     *Hasher
+		wantPrefix string
+	}{
+		{name: "argon2id", hasher: NewHasher(testArgon2), wantPrefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+		{name: "bcrypt", hasher: NewHasher(testBcrypt), wantPrefix: "$2a$04$"},
+		{name: "peppered argon2id", hasher: NewHasher(testArgon2).WithPepper(testPepper(t, "v1", "v1")), wantPrefix: "$pepper$k=v1$argon2id$"},
+		{name: "pepper without a current version", hasher: NewHasher(testArgon2).WithPepper(testPepper(t, "", "v1")), wantPrefix: "$argon2id$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := tt.hasher.Hash("secret")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+
+			if !strings.HasPrefix(first, tt.wantPrefix) {
+				t.Errorf("Hash = %q, want prefix %q", first, tt.wantPrefix)
+			}
+
+			second, err := tt.hasher.Hash("secret")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+
+			if first == second {
+				t.Errorf("two hashes of the same password are equal, the salt is not random")
+			}
+		})
+	}
+}
+
+func TestPepperLongPasswords(t *testing.T) {
+	// bcrypt alone only looks at the first 72 bytes
+	prefix := strings.Repeat("a", 72)
+
+	hasher := NewHasher(testBcrypt).WithPepper(testPepper(t, "v1", "v1"))
+
+	encoded, err := hasher.Hash(prefix + "first")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	ok, _, err := hasher.Verify(prefix+"second", encoded)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	if ok {
+		t.Errorf("passwords differing after 72 bytes verified as equal")
+	}
+}
+
+func TestNewPepper(t *testing.T) {
+	key := []byte(strings.Repeat("k", minPepperLength))
+
+	tests := []struct {
+		name    string
+		current string
+		keys    map[string][]byte
+		wantErr bool
+	}{
+		{name: "valid", current: "v1", keys: map[string][]byte{"v1": key}},
+		{name: "verify only", current: "", keys: map[string][]byte{"v1": key}},
+		{name: "no keys", current: "", keys: nil},
+		{name: "current unknown", current: "v2", keys: map[string][]byte{"v1": key}, wantErr: true},
+		{name: "short key", current: "v1", keys: map[string][]byte{"v1": key[:minPepperLength-1]}, wantErr: true},
+		{name: "empty version", current: "v1", keys: map[string][]byte{"v1": key, "": key}, wantErr: true},
+		{name: "version with a dollar", current: "v$1", keys: map[string][]byte{"v$1": key}, wantErr: true},
+		{name: "version with a space", current: "v 1", keys: map[string][]byte{"v 1": key}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPepper(tt.current, tt.keys); (err != nil) != tt.wantErr {
+				t.Errorf("NewPepper(%q) = %v, want error %v", tt.current, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPepper(t *testing.T) {
+	// base64 of sixteen bytes
+	const key = "MDEyMzQ1Njc4OWFiY2RlZg=="
+
+	tests := []struct {
+		name    string
+		content string
+		current string
+		wantErr bool
+	}{
+		{name: "valid", content: "# rotated yearly\n\nv1 " + key + "\nv2 " + key + "\n", current: "v2"},
+		{name: "missing key", content: "v1\n", current: "v1", wantErr: true},
+		{name: "extra field", content: "v1 " + key + " extra\n", current: "v1", wantErr: true},
+		{name: "not base64", content: "v1 not-base64!\n", current: "v1", wantErr: true},
+		{name: "duplicate version", content: "v1 " + key + "\nv1 " + key + "\n", current: "v1", wantErr: true},
+		{name: "current not in file", content: "v1 " + key + "\n", current: "v2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pepper")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := LoadPepper(path, tt.current); (err != nil) != tt.wantErr {
+				t.Errorf("LoadPepper = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
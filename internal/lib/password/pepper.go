@@ -0,0 +1,121 @@
+package password
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// pepperPrefix marks a peppered hash: $pepper$k=<version>$<hash of the scheme>
+const pepperPrefix = "$pepper$k="
+
+// minPepperLength is the shortest key accepted, in bytes
+const minPepperLength = 16
+
+var ErrUnknownPepper = errors.New("unknown pepper version")
+
+// Pepper is a set of server-side secrets, one per version, mixed into passwords with HMAC-SHA256
+// before they are hashed. The database alone is then not enough to crack the hashes. Old versions
+// are kept to verify hashes made with them until those are re-peppered
+type Pepper struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewPepper returns a pepper that peppers new hashes with the current version. An empty current
+// version only verifies hashes peppered before
+func NewPepper(current string, keys map[string][]byte) (*Pepper, error) {
+	for version, key := range keys {
+		if version == "" || strings.ContainsAny(version, "$ \t") {
+			return nil, fmt.Errorf("invalid pepper version %q", version)
+		}
+
+		if len(key) < minPepperLength {
+			return nil, fmt.Errorf("pepper version %s is shorter than %d bytes", version, minPepperLength)
+		}
+	}
+
+	if _, ok := keys[current]; current != "" && !ok {
+		return nil, fmt.Errorf("current pepper version %s: %w", current, ErrUnknownPepper)
+	}
+
+	return &Pepper{current: current, keys: keys}, nil
+}
+
+// LoadPepper reads a secret file of "<version> <base64 key>" lines. Blank lines and lines
+// starting with # are skipped
+func LoadPepper(path, current string) (*Pepper, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys := make(map[string][]byte)
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a version and a key", path, line)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: key is not base64", path, line)
+		}
+
+		if _, ok := keys[fields[0]]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate pepper version %s", path, line, fields[0])
+		}
+
+		keys[fields[0]] = key
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewPepper(current, keys)
+}
+
+// apply returns the password peppered with the given version, base64 encoded so that
+// every scheme, bcrypt included, gets the whole of it
+func (p *Pepper) apply(version, password string) ([]byte, error) {
+	key, ok := p.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPepper, version)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+
+	return []byte(base64.RawStdEncoding.EncodeToString(mac.Sum(nil))), nil
+}
+
+// splitPepper returns the pepper version of an encoded hash, empty if it is not peppered,
+// and the hash of the scheme
+func splitPepper(encoded string) (version, inner string) {
+	if !strings.HasPrefix(encoded, pepperPrefix) {
+		return "", encoded
+	}
+
+	rest := encoded[len(pepperPrefix):]
+
+	i := strings.IndexByte(rest, '$')
+	if i < 0 {
+		return "", encoded
+	}
+
+	return rest[:i], rest[i:]
+}
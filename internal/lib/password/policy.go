@@ -0,0 +1,228 @@
+package password
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules a password can violate, clients get them back to render their own messages
+const (
+	RuleMinLength        = "min_length"
+	RuleMaxLength        = "max_length"
+	RuleLowercase        = "lowercase"
+	RuleUppercase        = "uppercase"
+	RuleDigit            = "digit"
+	RuleSymbol           = "symbol"
+	RuleCharacterClasses = "character_classes"
+	RuleUserInfo         = "user_info"
+	RuleStrength         = "strength"
+	RuleBreached         = "breached"
+	RuleReused           = "reused"
+)
+
+// Policy is the set of rules new passwords have to satisfy
+type Policy struct {
+	MinLength           int
+	MaxLength           int
+	RequireLowercase    bool
+	RequireUppercase    bool
+	RequireDigit        bool
+	RequireSymbol       bool
+	MinCharacterClasses int
+	ForbidUserInfo      bool
+	MinStrength         int // 0 to 4, see Strength
+}
+
+// Violation is a rule a password breaks
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule a password breaks
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+
+	return "password does not satisfy the policy: " + strings.Join(messages, "; ")
+}
+
+// Check returns a *PolicyError if the password breaks any rule. The user info, such as
+// the username and email, must not appear in the password when ForbidUserInfo is set
+func (p Policy) Check(password string, userInfo ...string) error {
+	var violations []Violation
+
+	violate := func(rule, format string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		violate(RuleMinLength, "must be at least %d characters long", p.MinLength)
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violate(RuleMaxLength, "must be at most %d characters long", p.MaxLength)
+	}
+
+	classes := characterClasses(password)
+
+	if p.RequireLowercase && !classes.lower {
+		violate(RuleLowercase, "must contain a lowercase letter")
+	}
+
+	if p.RequireUppercase && !classes.upper {
+		violate(RuleUppercase, "must contain an uppercase letter")
+	}
+
+	if p.RequireDigit && !classes.digit {
+		violate(RuleDigit, "must contain a digit")
+	}
+
+	if p.RequireSymbol && !classes.symbol {
+		violate(RuleSymbol, "must contain a symbol")
+	}
+
+	if classes.count() < p.MinCharacterClasses {
+		violate(RuleCharacterClasses, "must mix at least %d of lowercase, uppercase, digits and symbols", p.MinCharacterClasses)
+	}
+
+	if p.ForbidUserInfo && containsUserInfo(password, userInfo) {
+		violate(RuleUserInfo, "must not contain your username or email")
+	}
+
+	if p.MinStrength > 0 && Strength(password) < p.MinStrength {
+		violate(RuleStrength, "is too easy to guess")
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+type classSet struct {
+	lower, upper, digit, symbol bool
+}
+
+func (c classSet) count() int {
+	n := 0
+	for _, present := range []bool{c.lower, c.upper, c.digit, c.symbol} {
+		if present {
+			n++
+		}
+	}
+
+	return n
+}
+
+func characterClasses(password string) classSet {
+	var c classSet
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			c.lower = true
+		case unicode.IsUpper(r):
+			c.upper = true
+		case unicode.IsDigit(r):
+			c.digit = true
+		default:
+			c.symbol = true
+		}
+	}
+
+	return c
+}
+
+// containsUserInfo looks for the user info, and the local part of emails, in the password
+func containsUserInfo(password string, userInfo []string) bool {
+	lower := strings.ToLower(password)
+
+	for _, info := range userInfo {
+		info = strings.ToLower(info)
+		if at := strings.IndexByte(info, '@'); at > 0 {
+			info = info[:at]
+		}
+
+		// Very short names would forbid too many passwords
+		if len(info) >= 3 && strings.Contains(lower, info) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Strength scores a password from 0 (trivial) to 4 (strong) by its estimated entropy.
+// The estimate takes the size of the alphabet the password draws from and discounts
+// characters that repeat or continue a sequence of the previous one
+func Strength(password string) int {
+	bits := Entropy(password)
+
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 60:
+		return 2
+	case bits < 128:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Entropy estimates the entropy of a password in bits
+func Entropy(password string) float64 {
+	classes := characterClasses(password)
+
+	alphabet := 0
+	if classes.lower {
+		alphabet += 26
+	}
+	if classes.upper {
+		alphabet += 26
+	}
+	if classes.digit {
+		alphabet += 10
+	}
+	if classes.symbol {
+		alphabet += 33
+	}
+
+	if alphabet == 0 {
+		return 0
+	}
+
+	perChar := math.Log2(float64(alphabet))
+
+	var bits float64
+	var prev rune = -1
+	for _, r := range password {
+		switch {
+		case r == prev:
+			// aaaa adds next to nothing
+			bits += 1
+		case prev >= 0 && (r == prev+1 || r == prev-1):
+			// abcd and 4321 are guessed early
+			bits += 2
+		default:
+			bits += perChar
+		}
+		prev = r
+	}
+
+	return bits
+}
@@ -0,0 +1,159 @@
+package password
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testPolicy() Policy {
+	return Policy{
+		MinLength:           8,
+		MaxLength:           64,
+		RequireLowercase:    true,
+		RequireUppercase:    true,
+		RequireDigit:        true,
+		MinCharacterClasses: 3,
+		ForbidUserInfo:      true,
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		userInfo []string
+		want     []string
+	}{
+		{
+			name:     "satisfies every rule",
+			policy:   testPolicy(),
+			password: "Str0ngPassw",
+			userInfo: []string{"johndoe", "john@example.com"},
+		},
+		{
+			name:     "every broken rule is reported",
+			policy:   testPolicy(),
+			password: "short",
+			want:     []string{RuleMinLength, RuleUppercase, RuleDigit, RuleCharacterClasses},
+		},
+		{
+			name:     "too few character classes",
+			policy:   testPolicy(),
+			password: "alllowercase1",
+			want:     []string{RuleUppercase, RuleCharacterClasses},
+		},
+		{
+			name:     "too long",
+			policy:   testPolicy(),
+			password: strings.Repeat("Aa1", 30),
+			want:     []string{RuleMaxLength},
+		},
+		{
+			name:     "no maximum",
+			policy:   Policy{MinLength: 8},
+			password: strings.Repeat("a", 1000),
+		},
+		{
+			name:     "length counts characters, not bytes",
+			policy:   testPolicy(),
+			password: "Пароль12",
+		},
+		{
+			name:     "missing symbol",
+			policy:   Policy{RequireSymbol: true},
+			password: "NoSymbols123",
+			want:     []string{RuleSymbol},
+		},
+		{
+			name:     "contains the username",
+			policy:   testPolicy(),
+			password: "xxJohnDoe123",
+			userInfo: []string{"johndoe"},
+			want:     []string{RuleUserInfo},
+		},
+		{
+			name:     "contains the local part of the email",
+			policy:   testPolicy(),
+			password: "Alice2024x",
+			userInfo: []string{"alice@example.com"},
+			want:     []string{RuleUserInfo},
+		},
+		{
+			name:     "very short user info is ignored",
+			policy:   testPolicy(),
+			password: "Al2024abcd",
+			userInfo: []string{"al"},
+		},
+		{
+			name:     "user info allowed",
+			policy:   Policy{},
+			password: "johndoe",
+			userInfo: []string{"johndoe"},
+		},
+		{
+			name:     "too easy to guess",
+			policy:   Policy{MinStrength: 3},
+			password: "abcdefghijkl",
+			want:     []string{RuleStrength},
+		},
+		{
+			name:     "strong enough",
+			policy:   Policy{MinStrength: 3},
+			password: "x7#Kq9!mZ2@v",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.password, tt.userInfo...)
+
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Check(%q) = %v, want nil", tt.password, err)
+				}
+				return
+			}
+
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Check(%q) = %v, want a *PolicyError", tt.password, err)
+			}
+
+			rules := make([]string, 0, len(policyErr.Violations))
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Rule)
+			}
+
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("Check(%q) broke %v, want %v", tt.password, rules, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrength(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     int
+	}{
+		{name: "empty", password: "", want: 0},
+		{name: "repeated character", password: "aaaaaaaaaaaa", want: 0},
+		{name: "sequence", password: "abcdefghijkl", want: 0},
+		{name: "common word", password: "password", want: 1},
+		{name: "lowercase words", password: "correcthorse", want: 2},
+		{name: "mixed classes", password: "Tr0ub4dor&3", want: 3},
+		{name: "long and random", password: "x7#Kq9!mZ2@vR5%tW8^y", want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Strength(tt.password); got != tt.want {
+				t.Errorf("Strength(%q) = %d (%.1f bits), want %d", tt.password, got, Entropy(tt.password), tt.want)
+			}
+		})
+	}
+}
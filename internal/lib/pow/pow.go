@@ -0,0 +1,173 @@
+package pow
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scopes a challenge is good for, a challenge issued for one cannot be spent on the other
+const (
+	ScopeLogin    = "login"
+	ScopeRegister = "register"
+)
+
+const version = "v1"
+
+var (
+	ErrInvalidChallenge = errors.New("invalid proof of work challenge")
+	ErrChallengeExpired = errors.New("proof of work challenge expired")
+	ErrInsufficientWork = errors.New("insufficient proof of work")
+)
+
+// Policy issues hashcash-style challenges: the client has to find a counter such that the
+// SHA-256 of "<challenge>:<counter>" starts with Difficulty zero bits. Challenges are signed
+// with Key and bound to the scope and the client address, the service keeps no state until
+// one is spent. Difficulty grows by Step bits every time the threat doubles past Threshold
+type Policy struct {
+	Enabled   bool
+	Key       []byte
+	TTL       time.Duration
+	Threshold int
+	Base      int
+	Step      int
+	Max       int
+}
+
+// Challenge is what a challenge string says once its signature checked out
+type Challenge struct {
+	Nonce      string
+	Scope      string
+	Difficulty int
+	ExpiresAt  time.Time
+}
+
+// Difficulty returns the number of zero bits to demand for a threat, such as the count
+// of recent failed logins. 0 means no proof of work is needed
+func (p Policy) Difficulty(threat int) int {
+	if !p.Enabled || p.Threshold <= 0 || threat < p.Threshold {
+		return 0
+	}
+
+	difficulty := p.Base
+	for level := threat / p.Threshold; level > 1; level /= 2 {
+		difficulty += p.Step
+	}
+
+	if p.Max > 0 && difficulty > p.Max {
+		return p.Max
+	}
+
+	return difficulty
+}
+
+// Issue returns a new challenge for the scope and the client address
+func (p Policy) Issue(scope, address string, difficulty int) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	payload := strings.Join([]string{
+		version,
+		hex.EncodeToString(nonce),
+		scope,
+		strconv.Itoa(difficulty),
+		strconv.FormatInt(time.Now().Add(p.TTL).Unix(), 10),
+	}, ".")
+
+	return payload + "." + p.sign(payload, address), nil
+}
+
+// Verify checks a "<challenge>:<counter>" solution made for the scope and the client address.
+// The challenge must ask for at least minDifficulty bits. It does not tell whether the
+// challenge was spent before, the caller has to remember the nonces it accepted
+func (p Policy) Verify(solution, scope, address string, minDifficulty int) (*Challenge, error) {
+	encoded, counter, found := strings.Cut(solution, ":")
+	if !found || counter == "" || len(counter) > 32 {
+		return nil, ErrInvalidChallenge
+	}
+
+	fields := strings.Split(encoded, ".")
+	if len(fields) != 6 || fields[0] != version {
+		return nil, ErrInvalidChallenge
+	}
+
+	payload := strings.Join(fields[:5], ".")
+	if !hmac.Equal([]byte(fields[5]), []byte(p.sign(payload, address))) {
+		return nil, ErrInvalidChallenge
+	}
+
+	difficulty, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	expiresAt, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	challenge := &Challenge{
+		Nonce:      fields[1],
+		Scope:      fields[2],
+		Difficulty: difficulty,
+		ExpiresAt:  time.Unix(expiresAt, 0),
+	}
+
+	if challenge.Scope != scope {
+		return nil, ErrInvalidChallenge
+	}
+
+	if !time.Now().Before(challenge.ExpiresAt) {
+		return nil, ErrChallengeExpired
+	}
+
+	if challenge.Difficulty < minDifficulty {
+		return nil, fmt.Errorf("%w: %d bits asked, %d needed", ErrInsufficientWork, challenge.Difficulty, minDifficulty)
+	}
+
+	if LeadingZeroBits(sha256.Sum256([]byte(solution))) < challenge.Difficulty {
+		return nil, ErrInsufficientWork
+	}
+
+	return challenge, nil
+}
+
+// Solve finds a solution to a challenge by brute force, the way clients are expected to
+func Solve(challenge string, difficulty int) string {
+	for counter := uint64(0); ; counter++ {
+		solution := challenge + ":" + strconv.FormatUint(counter, 10)
+		if LeadingZeroBits(sha256.Sum256([]byte(solution))) >= difficulty {
+			return solution
+		}
+	}
+}
+
+func LeadingZeroBits(sum [sha256.Size]byte) int {
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+
+		zeros += 8
+	}
+
+	return zeros
+}
+
+func (p Policy) sign(payload, address string) string {
+	mac := hmac.New(sha256.New, p.Key)
+	mac.Write([]byte(payload + "|" + address))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
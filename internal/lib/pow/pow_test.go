@@ -0,0 +1,212 @@
+package pow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPolicy() Policy {
+	return Policy{
+		Enabled:   true,
+		Key:       []byte("test key"),
+		TTL:       time.Minute,
+		Threshold: 5,
+		Base:      8,
+		Step:      2,
+		Max:       14,
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	tests := []struct {
+		threat int
+		want   int
+	}{
+		{threat: 0, want: 0},
+		{threat: 4, want: 0},
+		{threat: 5, want: 8},
+		{threat: 9, want: 8},
+		{threat: 10, want: 10},
+		{threat: 20, want: 12},
+		{threat: 40, want: 14},
+		{threat: 1000, want: 14},
+	}
+
+	for _, tt := range tests {
+		if got := testPolicy().Difficulty(tt.threat); got != tt.want {
+			t.Errorf("Difficulty(%d) = %d, want %d", tt.threat, got, tt.want)
+		}
+	}
+
+	disabled := testPolicy()
+	disabled.Enabled = false
+	if got := disabled.Difficulty(1000); got != 0 {
+		t.Errorf("disabled Difficulty(1000) = %d, want 0", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	policy := testPolicy()
+
+	challenge, err := policy.Issue(ScopeLogin, "203.0.113.7", 8)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	solution := Solve(challenge, 8)
+
+	expired := policy
+	expired.TTL = -time.Minute
+	expiredChallenge, err := expired.Issue(ScopeLogin, "203.0.113.7", 8)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	otherKey := policy
+	otherKey.Key = []byte("another key")
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		solution string
+		scope    string
+		address  string
+		min      int
+		wantErr  error
+	}{
+		{
+			name:     "valid solution",
+			policy:   policy,
+			solution: solution,
+			scope:    ScopeLogin,
+			address:  "203.0.113.7",
+			min:      8,
+		},
+		{
+			name:     "other address",
+			policy:   policy,
+			solution: solution,
+			scope:    ScopeLogin,
+			address:  "198.51.100.1",
+			min:      8,
+			wantErr:  ErrInvalidChallenge,
+		},
+		{
+			name:     "other scope",
+			policy:   policy,
+			solution: solution,
+			scope:    ScopeRegister,
+			address:  "203.0.113.7",
+			min:      8,
+			wantErr:  ErrInvalidChallenge,
+		},
+		{
+			name:     "other key",
+			policy:   otherKey,
+			solution: solution,
+			scope:    ScopeLogin,
+			address:  "203.0.113.7",
+			min:      8,
+			wantErr:  ErrInvalidChallenge,
+		},
+		{
+			name:     "harder challenge needed now",
+			policy:   policy,
+			solution: solution,
+			scope:    ScopeLogin,
+			address:  "203.0.113.7",
+			min:      10,
+			wantErr:  ErrInsufficientWork,
+		},
+		{
+			name:     "lowered difficulty",
+			policy:   policy,
+			solution: strings.Replace(solution, ".8.", ".1.", 1),
+			scope:    ScopeLogin,
+			address:  "203.0.113.7",
+			min:      1,
+			wantErr:  ErrInvalidChallenge,
+		},
+		{
+			name:     "expired",
+			policy:   policy,
+			solution: Solve(expiredChallenge, 8),
+			scope:    ScopeLogin,
+			address:  "203.0.113.7",
+			min:      8,
+			wantErr:  ErrChallengeExpired,
+		},
+		{
+			name:     "no counter",
+			policy:   policy,
+			solution: challenge,
+			scope:    ScopeLogin,
+			address:  "203.0.113.7",
+			min:      8,
+			wantErr:  ErrInvalidChallenge,
+		},
+		{
+			name:     "garbage",
+			policy:   policy,
+			solution: "v1.garbage:1",
+			scope:    ScopeLogin,
+			address:  "203.0.113.7",
+			min:      8,
+			wantErr:  ErrInvalidChallenge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Verify(tt.solution, tt.scope, tt.address, tt.min)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got.Scope != ScopeLogin || got.Difficulty != 8 || got.Nonce == "" {
+				t.Errorf("Verify() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsUnsolvedCounter(t *testing.T) {
+	policy := testPolicy()
+
+	challenge, err := policy.Issue(ScopeLogin, "203.0.113.7", 16)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// Counters that miss the target are overwhelmingly likely in a handful of tries
+	for counter := 0; counter < 8; counter++ {
+		solution := challenge + ":x" + strings.Repeat("0", counter)
+		if _, err := policy.Verify(solution, ScopeLogin, "203.0.113.7", 16); err == nil {
+			continue
+		} else if !errors.Is(err, ErrInsufficientWork) {
+			t.Fatalf("Verify() error = %v, want %v", err, ErrInsufficientWork)
+		}
+		return
+	}
+
+	t.Fatal("every arbitrary counter was accepted")
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	var sum [32]byte
+	if got := LeadingZeroBits(sum); got != 256 {
+		t.Errorf("LeadingZeroBits(zero) = %d, want 256", got)
+	}
+
+	sum[1] = 0x10
+	if got := LeadingZeroBits(sum); got != 11 {
+		t.Errorf("LeadingZeroBits() = %d, want 11", got)
+	}
+}
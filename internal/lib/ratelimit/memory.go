@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that filled up again are dropped
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rule   Rule
+}
+
+// Memory keeps the buckets in process, for a single instance
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *Memory) Allow(_ context.Context, buckets []Bucket) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	// Every bucket is checked before any is spent
+	left := make([]float64, len(buckets))
+	allowed := Result{Allowed: true, Remaining: math.MaxInt}
+	denied := Result{}
+
+	for i, b := range buckets {
+		tokens, last := b.Rule.capacity(), now
+		if state, ok := m.buckets[b.Key]; ok {
+			tokens, last = state.tokens, state.last
+		}
+
+		var result Result
+		left[i], result = take(tokens, last, now, b.Rule)
+
+		if !result.Allowed {
+			if result.RetryAfter >= denied.RetryAfter {
+				denied = Result{RetryAfter: result.RetryAfter, Exhausted: b}
+			}
+
+			continue
+		}
+
+		allowed.Remaining = min(allowed.Remaining, result.Remaining)
+	}
+
+	if denied.Exhausted.Key != "" {
+		return denied, nil
+	}
+
+	for i, b := range buckets {
+		m.buckets[b.Key] = &bucket{tokens: left[i], last: now, rule: b.Rule}
+	}
+
+	if len(buckets) == 0 {
+		allowed.Remaining = 0
+	}
+
+	return allowed, nil
+}
+
+// sweep drops the buckets that are full again, they are no different from new ones
+func (m *Memory) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.rule.perSecond() >= b.rule.capacity() {
+			delete(m.buckets, key)
+		}
+	}
+
+	m.lastSweep = now
+}
@@ -0,0 +1,344 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	// One token a second, ten at most
+	steady := Rule{Limit: 10, Period: 10 * time.Second}
+	// One token every four seconds, five at most
+	bursty := Rule{Limit: 1, Period: 4 * time.Second, Burst: 5}
+
+	last := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		rule       Rule
+		tokens     float64
+		elapsed    time.Duration
+		wantTokens float64
+		want       Result
+	}{
+		{
+			name:       "full bucket",
+			rule:       steady,
+			tokens:     10,
+			wantTokens: 9,
+			want:       Result{Allowed: true, Remaining: 9},
+		},
+		{
+			name:       "last token",
+			rule:       steady,
+			tokens:     1,
+			wantTokens: 0,
+			want:       Result{Allowed: true, Remaining: 0},
+		},
+		{
+			name:       "empty bucket",
+			rule:       steady,
+			tokens:     0,
+			wantTokens: 0,
+			want:       Result{RetryAfter: time.Second},
+		},
+		{
+			name:       "half a token",
+			rule:       steady,
+			tokens:     0.5,
+			wantTokens: 0.5,
+			want:       Result{RetryAfter: 500 * time.Millisecond},
+		},
+		{
+			name:       "refilled",
+			rule:       steady,
+			tokens:     0,
+			elapsed:    3 * time.Second,
+			wantTokens: 2,
+			want:       Result{Allowed: true, Remaining: 2},
+		},
+		{
+			name:       "refill stops at the capacity",
+			rule:       steady,
+			tokens:     5,
+			elapsed:    time.Hour,
+			wantTokens: 9,
+			want:       Result{Allowed: true, Remaining: 9},
+		},
+		{
+			name:       "burst over the limit",
+			rule:       bursty,
+			tokens:     5,
+			wantTokens: 4,
+			want:       Result{Allowed: true, Remaining: 4},
+		},
+		{
+			name:       "burst refills at the limit rate",
+			rule:       bursty,
+			tokens:     0,
+			wantTokens: 0,
+			want:       Result{RetryAfter: 4 * time.Second},
+		},
+		{
+			name:       "clock going backwards",
+			rule:       steady,
+			tokens:     2,
+			elapsed:    -time.Minute,
+			wantTokens: 1,
+			want:       Result{Allowed: true, Remaining: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, got := take(tt.tokens, last, last.Add(tt.elapsed), tt.rule)
+
+			if tokens != tt.wantTokens {
+				t.Errorf("take left %v tokens, want %v", tokens, tt.wantTokens)
+			}
+
+			if got != tt.want {
+				t.Errorf("take = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{name: "limit and period", rule: Rule{Limit: 5, Period: time.Minute}, want: true},
+		{name: "zero value", rule: Rule{}, want: false},
+		{name: "no period", rule: Rule{Limit: 5}, want: false},
+		{name: "no limit", rule: Rule{Period: time.Minute, Burst: 5}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Enabled(); got != tt.want {
+				t.Errorf("%+v.Enabled() = %v, want %v", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryAllow(t *testing.T) {
+	// Slow enough that no token comes back while the test runs
+	rule := Rule{Limit: 3, Period: time.Hour}
+
+	tests := []struct {
+		name  string
+		rule  Rule
+		calls []string
+		want  []bool
+	}{
+		{
+			name:  "allows up to the limit",
+			rule:  rule,
+			calls: []string{"a", "a", "a", "a", "a"},
+			want:  []bool{true, true, true, false, false},
+		},
+		{
+			name:  "keys have their own buckets",
+			rule:  rule,
+			calls: []string{"a", "a", "a", "b", "a", "b"},
+			want:  []bool{true, true, true, true, false, true},
+		},
+		{
+			name:  "burst",
+			rule:  Rule{Limit: 1, Period: time.Hour, Burst: 2},
+			calls: []string{"a", "a", "a"},
+			want:  []bool{true, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory()
+
+			for i, key := range tt.calls {
+				got, err := m.Allow(context.Background(), []Bucket{{Key: key, Rule: tt.rule}})
+				if err != nil {
+					t.Fatalf("Allow: %v", err)
+				}
+
+				if got.Allowed != tt.want[i] {
+					t.Errorf("call %d for %q allowed = %v, want %v", i, key, got.Allowed, tt.want[i])
+				}
+
+				if !got.Allowed && got.RetryAfter <= 0 {
+					t.Errorf("call %d for %q denied without a retry delay", i, key)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryAllowAll(t *testing.T) {
+	wide := Bucket{Dimension: "ip", Key: "ip:a", Rule: Rule{Limit: 3, Period: time.Hour}}
+	narrow := Bucket{Dimension: "method", Key: "method:m:ip:a", Rule: Rule{Limit: 1, Period: time.Hour}}
+	other := Bucket{Dimension: "method", Key: "method:n:ip:a", Rule: Rule{Limit: 3, Period: time.Hour}}
+
+	tests := []struct {
+		name  string
+		calls [][]Bucket
+		want  []Result
+	}{
+		{
+			name:  "remaining of the emptiest bucket",
+			calls: [][]Bucket{{wide, other}},
+			want:  []Result{{Allowed: true, Remaining: 2}},
+		},
+		{
+			name:  "refused calls spend nothing",
+			calls: [][]Bucket{{wide, narrow}, {wide, narrow}, {wide, narrow}, {wide, other}, {wide, other}},
+			want: []Result{
+				{Allowed: true, Remaining: 0},
+				{Exhausted: narrow},
+				{Exhausted: narrow},
+				{Allowed: true, Remaining: 1},
+				{Allowed: true, Remaining: 0},
+			},
+		},
+		{
+			name:  "no buckets",
+			calls: [][]Bucket{nil},
+			want:  []Result{{Allowed: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory()
+
+			for i, buckets := range tt.calls {
+				got, err := m.Allow(context.Background(), buckets)
+				if err != nil {
+					t.Fatalf("Allow: %v", err)
+				}
+
+				// The wait depends on the clock, only its presence is checked
+				if !got.Allowed && got.RetryAfter <= 0 {
+					t.Errorf("call %d denied without a retry delay", i)
+				}
+				got.RetryAfter = 0
+
+				if got != tt.want[i] {
+					t.Errorf("call %d = %+v, want %+v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLimitsBuckets(t *testing.T) {
+	rule := Rule{Limit: 1, Period: time.Second}
+
+	limits := Limits{
+		IP:      rule,
+		Subject: rule,
+		Methods: map[string]Rule{"/svc/Login": rule},
+	}
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		ip      string
+		subject string
+		method  string
+		want    []string
+	}{
+		{
+			name:   "anonymous call",
+			limits: limits,
+			ip:     "10.0.0.1",
+			method: "/svc/Login",
+			want:   []string{"ip:10.0.0.1", "method:/svc/Login:ip:10.0.0.1"},
+		},
+		{
+			name:    "signed in call",
+			limits:  limits,
+			ip:      "10.0.0.1",
+			subject: "user",
+			method:  "/svc/Login",
+			want:    []string{"ip:10.0.0.1", "sub:user", "method:/svc/Login:sub:user"},
+		},
+		{
+			name:   "method without a rule",
+			limits: limits,
+			ip:     "10.0.0.1",
+			method: "/svc/Logout",
+			want:   []string{"ip:10.0.0.1"},
+		},
+		{
+			name:    "unknown address",
+			limits:  limits,
+			subject: "user",
+			method:  "/svc/Logout",
+			want:    []string{"sub:user"},
+		},
+		{
+			name:   "nothing enabled",
+			ip:     "10.0.0.1",
+			method: "/svc/Login",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buckets := tt.limits.Buckets(tt.ip, tt.subject, tt.method)
+
+			var got []string
+			for _, b := range buckets {
+				got = append(got, b.Key)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Buckets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	rule := Rule{Limit: 2, Period: time.Minute}
+
+	m := NewMemory()
+	for _, key := range []string{"full", "drained", "drained"} {
+		if _, err := m.Allow(context.Background(), []Bucket{{Key: key, Rule: rule}}); err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  []string
+	}{
+		{name: "nothing refilled yet", after: 0, want: []string{"full", "drained"}},
+		{name: "one token back", after: 30 * time.Second, want: []string{"drained"}},
+		{name: "everything refilled", after: time.Minute, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.sweep(time.Now().Add(tt.after))
+
+			if len(m.buckets) != len(tt.want) {
+				t.Errorf("%d buckets left, want %v", len(m.buckets), tt.want)
+			}
+
+			for _, key := range tt.want {
+				if _, ok := m.buckets[key]; !ok {
+					t.Errorf("bucket %q was swept while still draining", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Rule is a token bucket: it holds Burst tokens at most and gets Limit tokens back every Period.
+// Every call takes a token, a call finding the bucket empty is over the limit
+type Rule struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// Enabled reports whether the rule limits anything
+func (r Rule) Enabled() bool {
+	return r.Limit > 0 && r.Period > 0
+}
+
+// capacity is the size of the bucket, Limit unless a burst is set
+func (r Rule) capacity() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+
+	return float64(r.Limit)
+}
+
+// perSecond is the refill rate of the bucket
+func (r Rule) perSecond() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// Bucket is the bucket at Key, following Rule. Dimension tells what it counts: ip, subject or method
+type Bucket struct {
+	Dimension string
+	Key       string
+	Rule      Rule
+}
+
+// Limits are the buckets every call takes a token from. IP and Subject are shared by all
+// methods, a method listed in Methods also has a bucket of its own per caller. A rule without
+// a limit is not applied
+type Limits struct {
+	IP      Rule
+	Subject Rule
+	Methods map[string]Rule
+}
+
+// Buckets returns the buckets of a call to method from ip, by subject when the caller is signed
+// in. Methods are limited per user once known, per address before
+func (l Limits) Buckets(ip, subject, method string) []Bucket {
+	caller := "ip:" + ip
+	if subject != "" {
+		caller = "sub:" + subject
+	}
+
+	var buckets []Bucket
+	if ip != "" {
+		buckets = append(buckets, Bucket{"ip", "ip:" + ip, l.IP})
+	}
+	if subject != "" {
+		buckets = append(buckets, Bucket{"subject", "sub:" + subject, l.Subject})
+	}
+	buckets = append(buckets, Bucket{"method", "method:" + method + ":" + caller, l.Methods[method]})
+
+	enabled := buckets[:0]
+	for _, b := range buckets {
+		if b.Rule.Enabled() {
+			enabled = append(enabled, b)
+		}
+	}
+
+	return enabled
+}
+
+// Result is the outcome of taking a token. Exhausted is the bucket that made a call wait the
+// longest when it is not allowed
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	Exhausted  Bucket
+}
+
+// Limiter keeps token buckets by key. Allow takes a token from every bucket, or from none of
+// them when one is empty, so that a refused call costs nothing
+type Limiter interface {
+	Allow(ctx context.Context, buckets []Bucket) (Result, error)
+}
+
+// take refills a bucket that had the given tokens at last, up to now, and takes a token from it
+func take(tokens float64, last, now time.Time, rule Rule) (float64, Result) {
+	rate := rule.perSecond()
+
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(rule.capacity(), tokens+elapsed*rate)
+	}
+
+	if tokens < 1 {
+		wait := time.Duration((1 - tokens) / rate * float64(time.Second))
+
+		return tokens, Result{RetryAfter: wait}
+	}
+
+	tokens--
+
+	return tokens, Result{Allowed: true, Remaining: int(tokens)}
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// tokenBucketScript takes a token from each bucket of KEYS in one step, or from none of them
+// when one is empty, timed by the redis clock so that every instance sees the same buckets.
+// ARGV holds the refill rate in tokens per millisecond and the capacity of each bucket in
+// turn. The result is whether the call is allowed, the tokens left in the emptiest bucket and,
+// when refused, the wait and the position of the bucket that needs it the longest. Needs
+// Redis 5 or later for TIME in a script that writes
+var tokenBucketScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local levels = {}
+local remaining = -1
+local retry = 0
+local exhausted = 0
+
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[i * 2 - 1])
+	local capacity = tonumber(ARGV[i * 2])
+
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local tokens = tonumber(state[1])
+	local ts = tonumber(state[2])
+	if tokens == nil or ts == nil then
+		tokens = capacity
+		ts = now
+	end
+
+	tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+	levels[i] = tokens
+
+	if tokens < 1 then
+		local wait = math.ceil((1 - tokens) / rate)
+		if wait >= retry then
+			retry = wait
+			exhausted = i
+		end
+	elseif remaining < 0 or tokens - 1 < remaining then
+		remaining = tokens - 1
+	end
+end
+
+if exhausted > 0 then
+	return {0, 0, retry, exhausted}
+end
+
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[i * 2 - 1])
+	local capacity = tonumber(ARGV[i * 2])
+
+	redis.call('HSET', key, 'tokens', tostring(levels[i] - 1), 'ts', now)
+	redis.call('PEXPIRE', key, math.ceil(capacity / rate) + 1000)
+end
+
+return {1, math.floor(math.max(0, remaining)), 0, 0}
+`)
+
+// Redis keeps the buckets in redis, shared by every instance of the service
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+func (r *Redis) Allow(ctx context.Context, buckets []Bucket) (Result, error) {
+	const op = "ratelimit.Redis.Allow"
+
+	if len(buckets) == 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	keys := make([]string, 0, len(buckets))
+	args := make([]any, 0, 2*len(buckets))
+	for _, b := range buckets {
+		keys = append(keys, r.prefix+b.Key)
+		args = append(args, b.Rule.perSecond()/1000, b.Rule.capacity())
+	}
+
+	values, err := tokenBucketScript.Run(ctx, r.client, keys, args...).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(values) != 4 || (values[0] != 1 && (values[3] < 1 || values[3] > int64(len(buckets)))) {
+		return Result{}, fmt.Errorf("%s: unexpected script result %v", op, values)
+	}
+
+	result := Result{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}
+	if !result.Allowed {
+		result.Remaining = 0
+		result.Exhausted = buckets[values[3]-1]
+	}
+
+	return result, nil
+}
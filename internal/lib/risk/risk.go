@@ -0,0 +1,100 @@
+package risk
+
+import (
+	"net"
+	"time"
+)
+
+type Decision string
+
+const (
+	DecisionAllow     Decision = "allow"
+	DecisionChallenge Decision = "challenge"
+	DecisionBlock     Decision = "block"
+)
+
+// Signals are the facts about a login attempt the score is computed from
+type Signals struct {
+	NewDevice      bool          `json:"new_device"`
+	NewIP          bool          `json:"new_ip"`
+	NewNetwork     bool          `json:"new_network"`
+	RecentFailures int           `json:"recent_failures"`
+	SinceLastLogin time.Duration `json:"since_last_login"`
+	FirstLogin     bool          `json:"first_login"`
+}
+
+// Policy holds the weight of every signal and the score thresholds for each decision
+type Policy struct {
+	Enabled            bool
+	NewDeviceWeight    int
+	NewIPWeight        int
+	NewNetworkWeight   int
+	FailureWeight      int
+	MaxFailureWeight   int
+	DormantWeight      int
+	DormantAfter       time.Duration
+	ChallengeThreshold int
+	BlockThreshold     int
+}
+
+type Assessment struct {
+	Score    int
+	Decision Decision
+}
+
+// Evaluate scores the signals against the policy and picks a decision
+func (p Policy) Evaluate(signals Signals) Assessment {
+	if !p.Enabled {
+		return Assessment{Decision: DecisionAllow}
+	}
+
+	score := 0
+
+	if signals.NewDevice {
+		score += p.NewDeviceWeight
+	}
+
+	// Without any history every ip and network is new, so only the device counts
+	if !signals.FirstLogin {
+		if signals.NewIP {
+			score += p.NewIPWeight
+		}
+
+		if signals.NewNetwork {
+			score += p.NewNetworkWeight
+		}
+
+		if p.DormantAfter > 0 && signals.SinceLastLogin > p.DormantAfter {
+			score += p.DormantWeight
+		}
+	}
+
+	failures := signals.RecentFailures * p.FailureWeight
+	if p.MaxFailureWeight > 0 && failures > p.MaxFailureWeight {
+		failures = p.MaxFailureWeight
+	}
+	score += failures
+
+	switch {
+	case p.BlockThreshold > 0 && score >= p.BlockThreshold:
+		return Assessment{Score: score, Decision: DecisionBlock}
+	case p.ChallengeThreshold > 0 && score >= p.ChallengeThreshold:
+		return Assessment{Score: score, Decision: DecisionChallenge}
+	default:
+		return Assessment{Score: score, Decision: DecisionAllow}
+	}
+}
+
+// Network returns the /24 of an IPv4 address or the /48 of an IPv6 address
+func Network(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
@@ -0,0 +1,133 @@
+package risk
+
+import (
+	"testing"
+	"time"
+)
+
+func testPolicy() Policy {
+	return Policy{
+		Enabled:            true,
+		NewDeviceWeight:    30,
+		NewIPWeight:        10,
+		NewNetworkWeight:   20,
+		FailureWeight:      10,
+		MaxFailureWeight:   40,
+		DormantWeight:      15,
+		DormantAfter:       90 * 24 * time.Hour,
+		ChallengeThreshold: 50,
+		BlockThreshold:     90,
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		signals Signals
+		want    Assessment
+	}{
+		{
+			name:    "disabled",
+			policy:  Policy{},
+			signals: Signals{NewDevice: true, NewIP: true, NewNetwork: true, RecentFailures: 100},
+			want:    Assessment{Decision: DecisionAllow},
+		},
+		{
+			name:    "known device and address",
+			policy:  testPolicy(),
+			signals: Signals{SinceLastLogin: time.Hour},
+			want:    Assessment{Decision: DecisionAllow},
+		},
+		{
+			name:    "new address on a known network",
+			policy:  testPolicy(),
+			signals: Signals{NewIP: true},
+			want:    Assessment{Score: 10, Decision: DecisionAllow},
+		},
+		{
+			name:    "new device on a new network",
+			policy:  testPolicy(),
+			signals: Signals{NewDevice: true, NewIP: true, NewNetwork: true},
+			want:    Assessment{Score: 60, Decision: DecisionChallenge},
+		},
+		{
+			name:    "exactly at the challenge threshold",
+			policy:  testPolicy(),
+			signals: Signals{NewDevice: true, NewNetwork: true},
+			want:    Assessment{Score: 50, Decision: DecisionChallenge},
+		},
+		{
+			name:    "first login only counts the device",
+			policy:  testPolicy(),
+			signals: Signals{NewDevice: true, NewIP: true, NewNetwork: true, FirstLogin: true, SinceLastLogin: 365 * 24 * time.Hour},
+			want:    Assessment{Score: 30, Decision: DecisionAllow},
+		},
+		{
+			name:    "dormant account",
+			policy:  testPolicy(),
+			signals: Signals{SinceLastLogin: 100 * 24 * time.Hour},
+			want:    Assessment{Score: 15, Decision: DecisionAllow},
+		},
+		{
+			name:    "dormancy not configured",
+			policy:  Policy{Enabled: true, DormantWeight: 15, ChallengeThreshold: 10},
+			signals: Signals{SinceLastLogin: 100 * 24 * time.Hour},
+			want:    Assessment{Decision: DecisionAllow},
+		},
+		{
+			name:    "failures are capped",
+			policy:  testPolicy(),
+			signals: Signals{RecentFailures: 50},
+			want:    Assessment{Score: 40, Decision: DecisionAllow},
+		},
+		{
+			name:    "uncapped failures",
+			policy:  Policy{Enabled: true, FailureWeight: 10, BlockThreshold: 90},
+			signals: Signals{RecentFailures: 9},
+			want:    Assessment{Score: 90, Decision: DecisionBlock},
+		},
+		{
+			name:    "everything at once",
+			policy:  testPolicy(),
+			signals: Signals{NewDevice: true, NewIP: true, NewNetwork: true, RecentFailures: 5, SinceLastLogin: 100 * 24 * time.Hour},
+			want:    Assessment{Score: 115, Decision: DecisionBlock},
+		},
+		{
+			name:    "no thresholds",
+			policy:  Policy{Enabled: true, NewDeviceWeight: 100},
+			signals: Signals{NewDevice: true},
+			want:    Assessment{Score: 100, Decision: DecisionAllow},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Evaluate(tt.signals); got != tt.want {
+				t.Errorf("Evaluate(%+v) = %+v, want %+v", tt.signals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want string
+	}{
+		{name: "ipv4", ip: "203.0.113.77", want: "203.0.113.0/24"},
+		{name: "ipv4 mapped ipv6", ip: "::ffff:203.0.113.77", want: "203.0.113.0/24"},
+		{name: "ipv6", ip: "2001:db8:abcd:12::1", want: "2001:db8:abcd::/48"},
+		{name: "empty", ip: "", want: ""},
+		{name: "not an address", ip: "localhost", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Network(tt.ip); got != tt.want {
+				t.Errorf("Network(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package account
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/jwt"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+	"log/slog"
+	"net/url"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxAvatarURLLength   = 512
+	maxMetadataEntries   = 32
+	maxMetadataKeyLength = 64
+	maxMetadataValLength = 512
+)
+
+type Account struct {
+	log               *slog.Logger
+	authenticator     Authenticator
+	profileRepository ProfileRepository
+}
+
+// Authenticator resolves an access token to its claims, it is implemented by the auth service
+type Authenticator interface {
+	Authenticate(ctx context.Context, accessToken string) (claims *jwt.AccessClaims, err error)
+}
+
+type ProfileRepository interface {
+	GetProfile(ctx context.Context, userId string) (profile *models.Profile, err error)
+	SaveProfile(ctx context.Context, profile *models.Profile, expectedVersion int64) (version int64, err error)
+}
+
+var (
+	ErrInvalidProfile  = errors.New("invalid profile")
+	ErrUnknownField    = errors.New("unknown profile field")
+	ErrVersionConflict = errors.New("profile was changed by another request")
+)
+
+func New(log *slog.Logger, authenticator Authenticator, profileRepository ProfileRepository) *Account {
+	return &Account{
+		log:               log,
+		authenticator:     authenticator,
+		profileRepository: profileRepository,
+	}
+}
+
+// GetProfile returns the profile of the caller, an empty one with version zero if nothing was saved yet
+func (a *Account) GetProfile(ctx context.Context, accessToken string) (*models.Profile, error) {
+	const op = "account.GetProfile"
+
+	claims, err := a.authenticator.Authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	profile, err := a.profile(ctx, claims.UserID)
+	if err != nil {
+		a.log.Error("failed to get profile", "op", op, "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return profile, nil
+}
+
+// UpdateProfile sets the fields named in the update mask. The update only applies if the
+// profile is still at the version the caller read, otherwise ErrVersionConflict is returned
+func (a *Account) UpdateProfile(ctx context.Context, accessToken string, update models.ProfileUpdate) (*models.Profile, error) {
+	const op = "account.UpdateProfile"
+
+	claims, err := a.authenticator.Authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", claims.UserID),
+	)
+
+	profile, err := a.profile(ctx, claims.UserID)
+	if err != nil {
+		log.Error("failed to get profile", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if profile.Version != update.Version {
+		return nil, fmt.Errorf("%s: %w", op, ErrVersionConflict)
+	}
+
+	if err := applyUpdate(profile, update); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := validateProfile(profile); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	version, err := a.profileRepository.SaveProfile(ctx, profile, update.Version)
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			return nil, fmt.Errorf("%s: %w", op, ErrVersionConflict)
+		}
+
+		log.Error("failed to save profile", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	profile.Version = version
+	profile.UpdatedAt = &now
+
+	log.Info("profile updated", "fields", update.Paths, "version", version)
+
+	return profile, nil
+}
+
+func (a *Account) profile(ctx context.Context, userId string) (*models.Profile, error) {
+	profile, err := a.profileRepository.GetProfile(ctx, userId)
+	if err == nil {
+		return profile, nil
+	}
+
+	if !errors.Is(err, storage.ErrProfileNotFound) {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Profile{UserID: id, Metadata: map[string]string{}}, nil
+}
+
+// applyUpdate copies the masked fields of the update into the profile
+func applyUpdate(profile *models.Profile, update models.ProfileUpdate) error {
+	if len(update.Paths) == 0 {
+		return fmt.Errorf("%w: update mask is empty", ErrInvalidProfile)
+	}
+
+	for _, path := range update.Paths {
+		switch path {
+		case models.ProfileDisplayName:
+			profile.DisplayName = update.Profile.DisplayName
+		case models.ProfileAvatarURL:
+			profile.AvatarURL = update.Profile.AvatarURL
+		case models.ProfileLocale:
+			profile.Locale = update.Profile.Locale
+		case models.ProfileTimezone:
+			profile.Timezone = update.Profile.Timezone
+		case models.ProfileMetadata:
+			profile.Metadata = update.Profile.Metadata
+			if profile.Metadata == nil {
+				profile.Metadata = map[string]string{}
+			}
+		default:
+			return fmt.Errorf("%w: %s", ErrUnknownField, path)
+		}
+	}
+
+	return nil
+}
+
+func validateProfile(profile *models.Profile) error {
+	if utf8.RuneCountInString(profile.DisplayName) > maxDisplayNameLength {
+		return fmt.Errorf("%w: display name is too long", ErrInvalidProfile)
+	}
+
+	if profile.AvatarURL != "" {
+		u, err := url.Parse(profile.AvatarURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(profile.AvatarURL) > maxAvatarURLLength {
+			return fmt.Errorf("%w: avatar url must be an http(s) url", ErrInvalidProfile)
+		}
+	}
+
+	if profile.Locale != "" {
+		tag, err := language.Parse(profile.Locale)
+		if err != nil {
+			return fmt.Errorf("%w: unknown locale", ErrInvalidProfile)
+		}
+
+		profile.Locale = tag.String()
+	}
+
+	if profile.Timezone != "" {
+		if _, err := time.LoadLocation(profile.Timezone); err != nil || profile.Timezone == "Local" {
+			return fmt.Errorf("%w: unknown timezone", ErrInvalidProfile)
+		}
+	}
+
+	if len(profile.Metadata) > maxMetadataEntries {
+		return fmt.Errorf("%w: too many metadata entries", ErrInvalidProfile)
+	}
+
+	for key, value := range profile.Metadata {
+		if key == "" || len(key) > maxMetadataKeyLength || len(value) > maxMetadataValLength {
+			return fmt.Errorf("%w: metadata entries are limited to %d byte keys and %d byte values",
+				ErrInvalidProfile, maxMetadataKeyLength, maxMetadataValLength)
+		}
+	}
+
+	return nil
+}
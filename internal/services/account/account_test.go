@@ -0,0 +1,173 @@
+package account
+
+import (
+	"AuthService/internal/domain/models"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyUpdate(t *testing.T) {
+	stored := func() models.Profile {
+		return models.Profile{
+			DisplayName: "Old Name",
+			AvatarURL:   "https://example.com/old.png",
+			Locale:      "en",
+			Timezone:    "UTC",
+			Metadata:    map[string]string{"theme": "dark"},
+		}
+	}
+
+	update := models.Profile{
+		DisplayName: "New Name",
+		Locale:      "de-DE",
+		Metadata:    map[string]string{"theme": "light"},
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		update  models.Profile
+		want    func() models.Profile
+		wantErr error
+	}{
+		{
+			name:   "only the masked field changes",
+			paths:  []string{models.ProfileDisplayName},
+			update: update,
+			want: func() models.Profile {
+				p := stored()
+				p.DisplayName = "New Name"
+				return p
+			},
+		},
+		{
+			name:   "several fields",
+			paths:  []string{models.ProfileLocale, models.ProfileMetadata},
+			update: update,
+			want: func() models.Profile {
+				p := stored()
+				p.Locale = "de-DE"
+				p.Metadata = map[string]string{"theme": "light"}
+				return p
+			},
+		},
+		{
+			name:   "masked field cleared by an empty value",
+			paths:  []string{models.ProfileAvatarURL, models.ProfileTimezone},
+			update: update,
+			want: func() models.Profile {
+				p := stored()
+				p.AvatarURL = ""
+				p.Timezone = ""
+				return p
+			},
+		},
+		{
+			name:   "metadata cleared by null",
+			paths:  []string{models.ProfileMetadata},
+			update: models.Profile{},
+			want: func() models.Profile {
+				p := stored()
+				p.Metadata = map[string]string{}
+				return p
+			},
+		},
+		{
+			name:    "empty mask",
+			paths:   nil,
+			wantErr: ErrInvalidProfile,
+		},
+		{
+			name:    "unknown field",
+			paths:   []string{models.ProfileDisplayName, "email"},
+			update:  update,
+			wantErr: ErrUnknownField,
+		},
+		{
+			name:    "fields are matched exactly",
+			paths:   []string{"displayName"},
+			wantErr: ErrUnknownField,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := stored()
+
+			err := applyUpdate(&profile, models.ProfileUpdate{Paths: tt.paths, Profile: tt.update})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("applyUpdate(%v) error = %v, want %v", tt.paths, err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if want := tt.want(); !reflect.DeepEqual(profile, want) {
+				t.Errorf("applyUpdate(%v) = %+v, want %+v", tt.paths, profile, want)
+			}
+		})
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	tooManyEntries := make(map[string]string, maxMetadataEntries+1)
+	for i := 0; i <= maxMetadataEntries; i++ {
+		tooManyEntries[strings.Repeat("k", i+1)] = "v"
+	}
+
+	tests := []struct {
+		name       string
+		profile    models.Profile
+		wantErr    bool
+		wantLocale string
+	}{
+		{name: "empty profile", profile: models.Profile{}},
+		{
+			name: "every field set",
+			profile: models.Profile{
+				DisplayName: "Jane",
+				AvatarURL:   "https://example.com/a.png",
+				Locale:      "en-GB",
+				Timezone:    "UTC",
+				Metadata:    map[string]string{"theme": "dark"},
+			},
+			wantLocale: "en-GB",
+		},
+		{name: "display name at the limit", profile: models.Profile{DisplayName: strings.Repeat("я", maxDisplayNameLength)}},
+		{name: "display name too long", profile: models.Profile{DisplayName: strings.Repeat("a", maxDisplayNameLength+1)}, wantErr: true},
+		{name: "avatar over plain http", profile: models.Profile{AvatarURL: "http://example.com/a.png"}},
+		{name: "avatar with another scheme", profile: models.Profile{AvatarURL: "javascript:alert(1)"}, wantErr: true},
+		{name: "avatar without a host", profile: models.Profile{AvatarURL: "https:///a.png"}, wantErr: true},
+		{name: "avatar url too long", profile: models.Profile{AvatarURL: "https://example.com/" + strings.Repeat("a", maxAvatarURLLength)}, wantErr: true},
+		{name: "locale is canonicalized", profile: models.Profile{Locale: "en-gb"}, wantLocale: "en-GB"},
+		{name: "unknown locale", profile: models.Profile{Locale: "not a locale"}, wantErr: true},
+		{name: "unknown timezone", profile: models.Profile{Timezone: "Mars/Olympus_Mons"}, wantErr: true},
+		{name: "server local timezone", profile: models.Profile{Timezone: "Local"}, wantErr: true},
+		{name: "too many metadata entries", profile: models.Profile{Metadata: tooManyEntries}, wantErr: true},
+		{name: "empty metadata key", profile: models.Profile{Metadata: map[string]string{"": "v"}}, wantErr: true},
+		{name: "metadata key too long", profile: models.Profile{Metadata: map[string]string{strings.Repeat("k", maxMetadataKeyLength+1): "v"}}, wantErr: true},
+		{name: "metadata value too long", profile: models.Profile{Metadata: map[string]string{"k": strings.Repeat("v", maxMetadataValLength+1)}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := tt.profile
+
+			err := validateProfile(&profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateProfile(%+v) = %v, want error %v", tt.profile, err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidProfile) {
+				t.Errorf("validateProfile(%+v) = %v, want ErrInvalidProfile", tt.profile, err)
+			}
+
+			if err == nil && profile.Locale != tt.wantLocale {
+				t.Errorf("locale = %q, want %q", profile.Locale, tt.wantLocale)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/abuse"
+	"AuthService/internal/lib/risk"
+	"AuthService/internal/lib/secret"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// tracksLoginFailures reports whether failed logins are kept, for abuse detection or for
+// scaling proof of work challenges
+func (a *Auth) tracksLoginFailures() bool {
+	return a.settings.AbusePolicy.Enabled || a.settings.ProofOfWork.Enabled
+}
+
+// sourceFailureStats counts the recent failed logins from the source of a request across
+// every account. Like the risk assessment, missing history must not lock everyone out,
+// failures to get it are logged and count as none
+func (a *Auth) sourceFailureStats(ctx context.Context, log *slog.Logger, client models.Client) models.LoginFailureStats {
+	if !a.tracksLoginFailures() || client.IP == "" {
+		return models.LoginFailureStats{}
+	}
+
+	stats, err := a.loginEventRepository.GetLoginFailureStats(
+		ctx,
+		client.IP,
+		risk.Network(client.IP),
+		time.Now().Add(-a.settings.AbusePolicy.Window),
+	)
+	if err != nil {
+		log.Error("failed to get login failure stats", "error", err)
+
+		return models.LoginFailureStats{}
+	}
+
+	return *stats
+}
+
+// checkLoginAbuse applies the abuse policy to the recent failed logins of a source. A slowed
+// down login is held here, a blocked one refused, a challenge is returned for the caller to apply
+func (a *Auth) checkLoginAbuse(ctx context.Context, log *slog.Logger, stats models.LoginFailureStats) (abuse.Response, error) {
+	response, detections := a.settings.AbusePolicy.Evaluate(stats)
+	if response == abuse.ResponseNone {
+		return response, nil
+	}
+
+	log.Warn("login from an abusive source",
+		slog.String("response", string(response)),
+		slog.Int("ipFailures", stats.IPFailures),
+		slog.Int("networkFailures", stats.NetworkFailures),
+		slog.Int("distinctUsernames", stats.DistinctUsernames),
+		slog.Int("detections", len(detections)),
+	)
+
+	switch response {
+	case abuse.ResponseBlock:
+		return response, ErrLoginBlocked
+	case abuse.ResponseSlowDown:
+		select {
+		case <-time.After(a.settings.AbusePolicy.SlowDownDelay):
+		case <-ctx.Done():
+			return response, ctx.Err()
+		}
+	}
+
+	return response, nil
+}
+
+// recordLoginFailure remembers a failed login of any account, known or not, and emits a
+// security event for every abuse rule this failure took the source over
+func (a *Auth) recordLoginFailure(ctx context.Context, log *slog.Logger, input string, client models.Client) {
+	policy := a.settings.AbusePolicy
+	if !a.tracksLoginFailures() || client.IP == "" {
+		return
+	}
+
+	now := time.Now()
+	network := risk.Network(client.IP)
+	since := now.Add(-policy.Window)
+
+	var before *models.LoginFailureStats
+	if policy.Enabled {
+		var err error
+
+		before, err = a.loginEventRepository.GetLoginFailureStats(ctx, client.IP, network, since)
+		if err != nil {
+			log.Error("failed to get login failure stats", "error", err)
+		}
+	}
+
+	failure := &models.LoginFailure{
+		IP:           client.IP,
+		Network:      network,
+		UsernameHash: secret.Hash(strings.ToLower(input)),
+		CreatedAt:    now,
+	}
+
+	if err := a.loginEventRepository.SaveLoginFailure(ctx, failure); err != nil {
+		log.Error("failed to save login failure", "error", err)
+
+		return
+	}
+
+	if before == nil {
+		return
+	}
+
+	after, err := a.loginEventRepository.GetLoginFailureStats(ctx, client.IP, network, since)
+	if err != nil {
+		log.Error("failed to get login failure stats", "error", err)
+
+		return
+	}
+
+	for _, detection := range policy.Crossed(*before, *after) {
+		log.Warn("login abuse detected",
+			slog.String("detector", detection.Detector),
+			slog.Int("count", detection.Count),
+			slog.String("response", string(detection.Response)),
+			slog.String("ip", client.IP),
+			slog.String("network", network),
+		)
+
+		a.audit(ctx, log, models.AuditLoginAbuseDetected, "", "", map[string]any{
+			"detector": detection.Detector,
+			"count":    detection.Count,
+			"response": detection.Response,
+			"ip":       client.IP,
+			"network":  network,
+			"window":   policy.Window.String(),
+		})
+	}
+}
+
+// PruneLoginFailures forgets the failed logins too old to count towards abuse detection
+func (a *Auth) PruneLoginFailures(ctx context.Context) error {
+	const op = "auth.PruneLoginFailures"
+
+	if !a.tracksLoginFailures() {
+		return nil
+	}
+
+	pruned, err := a.loginEventRepository.PruneLoginFailures(ctx, time.Now().Add(-a.settings.AbusePolicy.Window))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if pruned > 0 {
+		a.log.Info("login failures pruned", "op", op, "count", pruned)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/jwt"
+	"AuthService/internal/lib/secret"
+	"AuthService/internal/storage"
+	"AuthService/middlewares"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// authorizeAdmin returns the administrator the access token belongs to. The role is read
+// from storage so that revoking it takes effect without waiting for tokens to expire
+func (a *Auth) authorizeAdmin(ctx context.Context, accessToken string) (*models.User, error) {
+	const op = "auth.authorizeAdmin"
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if err := a.requireActiveSession(ctx, claims); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	admin, err := a.userRepository.GetUser(ctx, "id", claims.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if admin.Role != models.RoleAdmin {
+		a.log.Warn("admin operation attempted by non-admin", "op", op, "userId", claims.UserID)
+
+		return nil, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	return admin, nil
+}
+
+// CreateUser lets an administrator open an account on behalf of someone. With a temporary
+// password the admin passes it on, without one the user gets a link to set their password.
+// Either way the user has to choose a new password before the account can be used
+func (a *Auth) CreateUser(ctx context.Context, accessToken, login, email, temporaryPassword string) (string, error) {
+	const op = "auth.CreateUser"
+
+	admin, err := a.authorizeAdmin(ctx, accessToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+		slog.String("actorId", admin.ID.String()),
+	)
+
+	sendLink := temporaryPassword == ""
+	if sendLink {
+		// Nobody knows this password, the user sets theirs through the link
+		if temporaryPassword, _, err = secret.NewToken(); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	} else if err := a.checkNewPassword(temporaryPassword, login, email); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if status := middlewares.CheckRegister(login, email, temporaryPassword); status != true {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	if status, err := a.userRepository.CheckUsernameIsAvailable(ctx, login); status != true || err != nil {
+		if err != nil {
+			log.Error("failed to check username availability", "error", err)
+		}
+
+		return "", fmt.Errorf("%s: %w", op, ErrUserAlreadyExists)
+	}
+
+	if status, err := a.userRepository.CheckEmailIsAvailable(ctx, email); status != true || err != nil {
+		if err != nil {
+			log.Error("failed to check email availability", "error", err)
+		}
+
+		return "", fmt.Errorf("%s: %w", op, ErrUserAlreadyExists)
+	}
+
+	passHash, err := a.hasher.Hash(temporaryPassword)
+	if err != nil {
+		log.Error("failed to hash password", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	uid, err := middlewares.UUIDGenerator()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	userId, err := a.userRepository.SaveCreatedUser(ctx, uid, login, email, []byte(passHash), admin.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserAlreadyExists) {
+			return "", fmt.Errorf("%s: %w", op, ErrUserAlreadyExists)
+		}
+
+		log.Error("failed to save user", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.audit(ctx, log, models.AuditAccountCreated, userId, admin.ID.String(), map[string]any{
+		"set_password_link": sendLink,
+	})
+
+	user := &models.User{ID: uid, Username: login, Email: email}
+
+	if sendLink {
+		if err := a.sendSetPasswordLink(ctx, user); err != nil {
+			// The account exists, the admin can fall back to a password reset
+			log.Error("failed to send set-password link", "error", err)
+		}
+	}
+
+	if err := a.sendVerificationEmail(ctx, user); err != nil {
+		log.Error("failed to send verification email", "error", err)
+	}
+
+	log.Info("user created", "id", userId)
+
+	return userId, nil
+}
+
+func (a *Auth) sendSetPasswordLink(ctx context.Context, user *models.User) error {
+	const op = "auth.sendSetPasswordLink"
+
+	link, err := a.passwordResetLink(ctx, user.ID.String(), a.settings.SetPasswordTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nan account has been created for you. Open the link below to choose your password:\n\n%s\n\nThe link expires in %s and can be used once.\n",
+		user.Username,
+		link,
+		a.settings.SetPasswordTTL,
+	)
+
+	if err := a.mailer.Send(ctx, user.Email, "Set your password", body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"log/slog"
+	"time"
+)
+
+// newAuditEvent builds an audit trail entry. Empty user or actor ids are stored as null
+func newAuditEvent(action, userId, actorId string, details map[string]any) *models.AuditEvent {
+	event := &models.AuditEvent{
+		Action:    action,
+		Details:   []byte("{}"),
+		CreatedAt: time.Now(),
+	}
+
+	if id, err := uuid.Parse(userId); err == nil {
+		event.UserID = &id
+	}
+
+	if id, err := uuid.Parse(actorId); err == nil {
+		event.ActorID = &id
+	}
+
+	if len(details) > 0 {
+		if raw, err := json.Marshal(details); err == nil {
+			event.Details = raw
+		}
+	}
+
+	return event
+}
+
+// audit records an action in the audit trail. Failing to do so is logged but never fails the action
+func (a *Auth) audit(ctx context.Context, log *slog.Logger, action, userId, actorId string, details map[string]any) {
+	if err := a.auditRepository.SaveAuditEvent(ctx, newAuditEvent(action, userId, actorId, details)); err != nil {
+		log.Error("failed to save audit event", "action", action, "error", err)
+	}
+}
@@ -2,51 +2,330 @@ package auth
 
 import (
 	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/abuse"
+	"AuthService/internal/lib/breach"
 	"AuthService/internal/lib/jwt"
+	"AuthService/internal/lib/mailer"
+	"AuthService/internal/lib/password"
+	"AuthService/internal/lib/pow"
+	"AuthService/internal/lib/risk"
+	"AuthService/internal/lib/secret"
 	"AuthService/internal/storage"
 	"AuthService/middlewares"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"log/slog"
+	"sync"
 	"time"
 )
 
 type Auth struct {
-	log            *slog.Logger
-	userRepository UserRepository
-	tokenTTL       time.Duration
+	log                     *slog.Logger
+	userRepository          UserRepository
+	deviceRepository        DeviceRepository
+	loginEventRepository    LoginEventRepository
+	sessionRepository       SessionRepository
+	passwordResetRepository PasswordResetRepository
+	emailChangeRepository   EmailChangeRepository
+	auditRepository         AuditRepository
+	dataExportRepository    DataExportRepository
+	invitationRepository    InvitationRepository
+	policyRepository        PolicyRepository
+	lockoutRepository       LockoutRepository
+	challengeRepository     ChallengeRepository
+	hasher                  *password.Hasher
+	breachChecker           breach.Checker
+	mailer                  mailer.Mailer
+	tokenTTL                time.Duration
+	settings                Settings
+
+	dummyHashOnce sync.Once
+	dummyHash     string
+}
+
+// Settings holds the tunable security policies of the Auth service
+type Settings struct {
+	DeviceTTL         time.Duration
+	RiskPolicy        risk.Policy
+	AbusePolicy       abuse.Policy
+	ProofOfWork       pow.Policy
+	PasswordPolicy    password.Policy
+	PasswordHistory   int
+	PasswordMaxAge    time.Duration
+	PasswordWarning   time.Duration
+	RiskFailureWindow time.Duration
+	StepUpMaxAge      time.Duration
+	StepUpACR         string
+
+	RequireVerifiedEmail bool
+	VerificationTTL      time.Duration
+	VerificationURL      string
+
+	RefreshTokenTTL time.Duration
+	ResetTTL        time.Duration
+	ResetURL        string
+	SetPasswordTTL  time.Duration
+
+	EmailChangeTTL       time.Duration
+	EmailChangeRevertTTL time.Duration
+	EmailChangeURL       string
+	EmailRevertURL       string
+
+	DeletionGracePeriod time.Duration
+	DeletionAnonymize   bool
+
+	ExportTTL time.Duration
+	// ExportLease is how long a worker may take over an export before another one takes it over
+	ExportLease time.Duration
+
+	UsernameReservation    time.Duration
+	UsernameChangeInterval time.Duration
+
+	RegistrationMode string
+	InvitationTTL    time.Duration
+	InvitationURL    string
+
+	LockoutThreshold   int
+	LockoutBaseDelay   time.Duration
+	LockoutMaxDelay    time.Duration
+	LockoutDuration    time.Duration
+	LockoutMaxDuration time.Duration
+	LockoutResetAfter  time.Duration
+
+	AntiEnumeration bool
 }
 
 type UserRepository interface {
 	SaveUser(ctx context.Context, id uuid.UUID, login, email string, password []byte) (uid string, err error)
 	GetUser(ctx context.Context, inputType, input string) (user *models.User, err error)
 	CheckUsernameIsAvailable(ctx context.Context, login string) (status bool, err error)
+	CheckUsernameIsAvailableFor(ctx context.Context, userId, login string) (status bool, err error)
 	CheckEmailIsAvailable(ctx context.Context, email string) (status bool, err error)
 	CheckUserByEmail(ctx context.Context, userId, email string) error
-	CheckUserByPassword(ctx context.Context, userId, password string) (hashPassword string, err error)
 	UpdateEmail(ctx context.Context, userId, email string) error
-	UpdatePassword(ctx context.Context, userId, password string) error
+	UpdatePassword(ctx context.Context, userId, password string, historySize int) error
+	ListPasswordHistory(ctx context.Context, userId string, limit uint64) ([]string, error)
+	MarkEmailVerified(ctx context.Context, userId, email string) error
+	ScheduleUserDeletion(ctx context.Context, userId string, at time.Time) error
+	CancelUserDeletion(ctx context.Context, userId string) error
+	ListUsersDueForDeletion(ctx context.Context, before time.Time, limit uint64) (userIds []string, err error)
+	EraseUser(ctx context.Context, userId string, anonymize bool, tombstone *models.AuditEvent) error
+	SetUserStatus(ctx context.Context, userId string, status models.AccountStatus) error
+	RehashPassword(ctx context.Context, userId, oldHash, newHash string) error
+	SaveCreatedUser(
+		ctx context.Context,
+		id uuid.UUID,
+		login, email string,
+		password []byte,
+		createdBy uuid.UUID,
+	) (uid string, err error)
+	ChangeUsername(ctx context.Context, userId, username string, reservedUntil time.Time) error
+	ListUsernameHistory(ctx context.Context, userId string) (changes []models.UsernameChange, err error)
+	GetProfile(ctx context.Context, userId string) (profile *models.Profile, err error)
+}
+
+type DeviceRepository interface {
+	SaveTrustedDevice(ctx context.Context, device *models.TrustedDevice) error
+	GetTrustedDevice(ctx context.Context, userId, deviceId string) (device *models.TrustedDevice, err error)
+	ListTrustedDevices(ctx context.Context, userId string) (devices []models.TrustedDevice, err error)
+	TouchTrustedDevice(ctx context.Context, deviceId string) error
+	DeleteTrustedDevices(ctx context.Context, userId string, deviceIds ...string) (deleted int64, err error)
+}
+
+type LoginEventRepository interface {
+	SaveLoginEvent(ctx context.Context, event *models.LoginEvent) error
+	GetLoginStats(ctx context.Context, userId, ip, network string, failuresSince time.Time) (stats *models.LoginStats, err error)
+	ListLoginEvents(ctx context.Context, userId string) (events []models.LoginEvent, err error)
+	SaveLoginFailure(ctx context.Context, failure *models.LoginFailure) error
+	GetLoginFailureStats(ctx context.Context, ip, network string, since time.Time) (stats *models.LoginFailureStats, err error)
+	PruneLoginFailures(ctx context.Context, before time.Time) (pruned int64, err error)
+}
+
+type SessionRepository interface {
+	SaveSession(ctx context.Context, session *models.Session) error
+	IsSessionActive(ctx context.Context, sessionId string) (active bool, err error)
+	RevokeUserSessions(ctx context.Context, userId string) (revoked int64, err error)
+	ListUserSessions(ctx context.Context, userId string) (sessions []models.Session, err error)
+}
+
+type PasswordResetRepository interface {
+	SavePasswordResetToken(ctx context.Context, userId, tokenHash string, expiresAt time.Time) error
+	GetPasswordResetToken(ctx context.Context, tokenHash string) (userId string, err error)
+	ResetPassword(ctx context.Context, tokenHash, password string, historySize int) (userId string, err error)
+}
+
+type EmailChangeRepository interface {
+	SaveEmailChange(ctx context.Context, change *models.EmailChange) error
+	GetEmailChangeToConfirm(ctx context.Context, confirmTokenHash string) (change *models.EmailChange, err error)
+	GetEmailChangeToRevert(ctx context.Context, revertTokenHash string) (change *models.EmailChange, err error)
+	ConfirmEmailChange(ctx context.Context, confirmTokenHash string) (change *models.EmailChange, err error)
+	RevertEmailChange(ctx context.Context, revertTokenHash string) (change *models.EmailChange, err error)
+	CancelPendingEmailChanges(ctx context.Context, userId string) error
+	ListEmailChanges(ctx context.Context, userId string) (changes []models.EmailChange, err error)
+}
+
+type AuditRepository interface {
+	SaveAuditEvent(ctx context.Context, event *models.AuditEvent) error
+	ListAuditEvents(ctx context.Context, userId string) (events []models.AuditEvent, err error)
+}
+
+type InvitationRepository interface {
+	SaveInvitation(ctx context.Context, invitation *models.Invitation) error
+	RevokeInvitation(ctx context.Context, invitationId string) error
+	SaveInvitedUser(
+		ctx context.Context,
+		id uuid.UUID,
+		login, email string,
+		password []byte,
+		codeHash string,
+	) (invitation *models.Invitation, err error)
+}
+
+type PolicyRepository interface {
+	SavePolicyDocument(ctx context.Context, document *models.PolicyDocument) error
+	ListCurrentPolicies(ctx context.Context) (documents []models.PolicyDocument, err error)
+	ListPendingPolicies(ctx context.Context, userId string) (documents []models.PolicyDocument, err error)
+	SavePolicyAcceptances(
+		ctx context.Context,
+		userId string,
+		documentIds []string,
+		acceptedAt time.Time,
+		ip, userAgent string,
+	) error
+	ListPolicyAcceptances(ctx context.Context, userId string) (acceptances []models.PolicyAcceptance, err error)
+}
+
+type LockoutRepository interface {
+	GetLoginLockout(ctx context.Context, subject string) (lockout *models.LoginLockout, err error)
+	ReserveLoginAttempt(
+		ctx context.Context,
+		subject, userId string,
+		seen *models.LoginLockout,
+		at, resetBefore time.Time,
+	) (lockout *models.LoginLockout, reserved bool, err error)
+	LockLogins(ctx context.Context, subject string, until time.Time) error
+	ClearLoginLockout(ctx context.Context, subject string) (cleared bool, err error)
+	PruneUnknownLogins(ctx context.Context, before time.Time) (pruned int64, err error)
+}
+
+type ChallengeRepository interface {
+	RedeemChallenge(ctx context.Context, nonce string, expiresAt time.Time) (redeemed bool, err error)
+	PruneChallenges(ctx context.Context, before time.Time) (pruned int64, err error)
+}
+
+type DataExportRepository interface {
+	SaveDataExport(ctx context.Context, export *models.DataExport) error
+	GetDataExport(ctx context.Context, userId, exportId string) (export *models.DataExport, err error)
+	ClaimPendingDataExport(ctx context.Context, leaseBefore time.Time) (export *models.DataExport, err error)
+	FinishDataExport(ctx context.Context, export *models.DataExport) error
+	DeleteExpiredDataExports(ctx context.Context, before time.Time) (deleted int64, err error)
 }
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserAlreadyExists  = errors.New("user already exists")
 	ErrUserNotFound       = errors.New("user not found")
+
+	ErrDeviceNotFound            = errors.New("device not found")
+	ErrDeviceFingerprintRequired = errors.New("device fingerprint is required")
+
+	ErrMFARequired  = errors.New("additional verification required")
+	ErrLoginBlocked = errors.New("login blocked")
+
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrStepUpRequired = errors.New("step-up authentication required")
+
+	ErrEmailNotVerified  = errors.New("email not verified")
+	ErrEmailAlreadyTaken = errors.New("email already taken")
+
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrInvalidStatus    = errors.New("invalid account status")
+	ErrAccountSuspended = errors.New("account suspended")
+	ErrAccountLocked    = errors.New("account locked")
+	ErrAccountBanned    = errors.New("account banned")
+
+	ErrInvalidExportFormat = errors.New("invalid export format")
+	ErrExportNotFound      = errors.New("export not found")
+
+	ErrInvalidUsername       = errors.New("invalid username")
+	ErrUsernameTaken         = errors.New("username already taken")
+	ErrUsernameChangeTooSoon = errors.New("username changed too recently")
+
+	ErrRegistrationClosed = errors.New("registration is closed")
+	ErrInvitationRequired = errors.New("invitation required")
+	ErrInvalidInvitation  = errors.New("invalid invitation")
+	ErrInvitationNotFound = errors.New("invitation not found")
+
+	ErrPasswordChangeRequired = errors.New("password change required")
+	ErrPasswordExpired        = errors.New("password expired")
+
+	ErrInvalidPolicy            = errors.New("invalid policy")
+	ErrPolicyExists             = errors.New("policy version already exists")
+	ErrPolicyAcceptanceRequired = errors.New("policy acceptance required")
+
+	ErrTooManyAttempts = errors.New("too many failed login attempts")
+	ErrLockoutNotFound = errors.New("lockout not found")
+
+	ErrProofOfWorkRequired = errors.New("proof of work required")
 )
 
 // New return a new instance of the Auth service
-func New(log *slog.Logger, userRepository UserRepository, tokenTTL time.Duration) *Auth {
+// Repositories are the stores the Auth service keeps its data in
+type Repositories struct {
+	Users          UserRepository
+	Devices        DeviceRepository
+	LoginEvents    LoginEventRepository
+	Sessions       SessionRepository
+	PasswordResets PasswordResetRepository
+	EmailChanges   EmailChangeRepository
+	Audit          AuditRepository
+	DataExports    DataExportRepository
+	Invitations    InvitationRepository
+	Policies       PolicyRepository
+	Lockouts       LockoutRepository
+	Challenges     ChallengeRepository
+}
+
+func New(
+	log *slog.Logger,
+	repositories Repositories,
+	mailer mailer.Mailer,
+	hasher *password.Hasher,
+	breachChecker breach.Checker,
+	tokenTTL time.Duration,
+	settings Settings,
+) *Auth {
 	return &Auth{
-		log:            log,
-		userRepository: userRepository,
-		tokenTTL:       tokenTTL,
+		log:                     log,
+		userRepository:          repositories.Users,
+		deviceRepository:        repositories.Devices,
+		loginEventRepository:    repositories.LoginEvents,
+		sessionRepository:       repositories.Sessions,
+		passwordResetRepository: repositories.PasswordResets,
+		emailChangeRepository:   repositories.EmailChanges,
+		auditRepository:         repositories.Audit,
+		dataExportRepository:    repositories.DataExports,
+		invitationRepository:    repositories.Invitations,
+		policyRepository:        repositories.Policies,
+		lockoutRepository:       repositories.Lockouts,
+		challengeRepository:     repositories.Challenges,
+		hasher:                  hasher,
+		breachChecker:           breachChecker,
+		mailer:                  mailer,
+		tokenTTL:                tokenTTL,
+		settings:                settings,
 	}
 }
 
-func (a *Auth) Register(ctx context.Context, login, email, password string) (userID string, err error) {
+// Register creates an account. Depending on the registration mode an invitation code is
+// required, one given in open mode is used all the same to record who invited the user
+func (a *Auth) Register(
+	ctx context.Context,
+	login, email, password, invitationCode string,
+	client models.Client,
+) (userID string, err error) {
 	const op = "auth.Register"
 
 	log := a.log.With(
@@ -54,31 +333,62 @@ func (a *Auth) Register(ctx context.Context, login, email, password string) (use
 		slog.String("email", email),
 	)
 
+	switch a.settings.RegistrationMode {
+	case models.RegistrationClosed:
+		return "", fmt.Errorf("%s: %w", op, ErrRegistrationClosed)
+	case models.RegistrationInviteOnly:
+		if invitationCode == "" {
+			return "", fmt.Errorf("%s: %w", op, ErrInvitationRequired)
+		}
+	}
+
 	if status := middlewares.CheckRegister(login, email, password); status != true {
 		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
+	// Sign-ups from a source failing logins all over the place are likely scripted
+	if a.settings.ProofOfWork.Enabled {
+		threat := a.sourceFailureStats(ctx, log, client).IPFailures
+		if _, err := a.requireProofOfWork(ctx, log, pow.ScopeRegister, client, threat); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	usernameTaken := false
 	if status, err := a.userRepository.CheckUsernameIsAvailable(ctx, login); status != true || err != nil {
 		if err != nil {
-			log.Error("this username already taken", err)
+			log.Error("this username already taken", "error", err)
+		}
+
+		if !a.settings.AntiEnumeration {
+			return "", fmt.Errorf("%s: %w", op, ErrUserAlreadyExists)
 		}
 
-		return "", fmt.Errorf("%s: %w", op, ErrUserAlreadyExists)
+		usernameTaken = true
 	}
 
+	emailTaken := false
 	if status, err := a.userRepository.CheckEmailIsAvailable(ctx, email); status != true || err != nil {
 		if err != nil {
-			log.Error("this email already taken", err)
+			log.Error("this email already taken", "error", err)
 		}
 
-		return "", fmt.Errorf("%s: %w", op, ErrUserAlreadyExists)
+		if !a.settings.AntiEnumeration {
+			return "", fmt.Errorf("%s: %w", op, ErrUserAlreadyExists)
+		}
+
+		emailTaken = true
+	}
+
+	if err := a.checkNewPassword(password, login, email); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("registering new user")
 
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	passHash, err := a.hasher.Hash(password)
 	if err != nil {
-		log.Error("failed to hash password", err)
+		log.Error("failed to hash password", "error", err)
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
@@ -88,25 +398,67 @@ func (a *Auth) Register(ctx context.Context, login, email, password string) (use
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	id, err := a.userRepository.SaveUser(ctx, uid, login, email, passHash)
+	// The caller gets an id like any new user, the owner of the address hears about it
+	switch {
+	case emailTaken:
+		a.notifyExistingAccount(ctx, log, email)
+
+		return uid.String(), nil
+	case usernameTaken:
+		a.notifyUsernameTaken(ctx, log, login, email)
+
+		return uid.String(), nil
+	}
+
+	if invitationCode == "" {
+		userID, err = a.userRepository.SaveUser(ctx, uid, login, email, []byte(passHash))
+	} else {
+		var invitation *models.Invitation
+		invitation, err = a.invitationRepository.SaveInvitedUser(ctx, uid, login, email, []byte(passHash), secret.Hash(invitationCode))
+		if err == nil {
+			userID = uid.String()
+
+			a.audit(ctx, log, models.AuditAccountRegistered, userID, userID, map[string]any{
+				"invitation_id": invitation.ID,
+				"invited_by":    invitation.CreatedBy,
+			})
+		}
+	}
 	if err != nil {
+		if errors.Is(err, storage.ErrInvitationNotFound) {
+			log.Info("invalid invitation used")
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidInvitation)
+		}
+
 		if errors.Is(err, storage.ErrUserAlreadyExists) {
-			a.log.Warn("user already exists", err)
+			a.log.Warn("user already exists", "error", err)
 
 			return "", fmt.Errorf("%s: %w", op, err)
 		}
 
-		log.Error("failed to save user", err)
+		log.Error("failed to save user", "error", err)
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Info("user registered", "id", id)
+	log.Info("user registered", "id", userID)
+
+	// Signing up means agreeing to the policies in force, a failure here only means
+	// the user is asked again at their first login
+	if err := a.acceptCurrentPolicies(ctx, userID, client); err != nil {
+		log.Error("failed to record policy acceptance", "error", err)
+	}
+
+	// The account exists at this point, a failed email can be resent later
+	if err := a.sendVerificationEmail(ctx, &models.User{ID: uid, Username: login, Email: email}); err != nil {
+		log.Error("failed to send verification email", "error", err)
+	}
 
-	return id, nil
+	return userID, nil
 }
 
-func (a *Auth) Login(ctx context.Context, input, password string) (string, string, error) {
+func (a *Auth) Login(ctx context.Context, input, password string, client models.Client) (*models.LoginResult, error) {
 	const op = "auth.Login"
 
 	log := a.log.With(
@@ -115,49 +467,177 @@ func (a *Auth) Login(ctx context.Context, input, password string) (string, strin
 	)
 
 	if status := middlewares.CheckLogin(input, password); status != true {
-		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
 	log.Info("logging in")
 
+	stats := a.sourceFailureStats(ctx, log, client)
+
+	abuseResponse, err := a.checkLoginAbuse(ctx, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	inputType := middlewares.IdentifyLoginInputType(input)
 
 	user, err := a.userRepository.GetUser(ctx, inputType, input)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		a.log.Error("failed to get user", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	subject := a.lockoutSubject(user, input)
+
+	// The work is asked for before an unknown account is told apart from a wrong password
+	proven, err := a.requireProofOfWork(ctx, log, pow.ScopeLogin, client, a.loginThreat(ctx, log, subject, stats, abuseResponse))
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			a.log.Warn("user not found", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-			return "", "", fmt.Errorf("%s: %w", op, err)
-		}
+	if user == nil && !a.settings.AntiEnumeration {
+		a.log.Warn("user not found")
+
+		a.recordLoginFailure(ctx, log, input, client)
+
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
 
-		a.log.Error("failed to get user", err)
+	// In anti-enumeration mode an unknown account goes through the same lockout, hashing
+	// and writes as a wrong password, so neither the answer nor its timing tell them apart
+	lockout, err := a.reserveLoginAttempt(ctx, subject, user)
+	if err != nil {
+		log.Info("login refused after failed attempts", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-		return "", "", fmt.Errorf("%s: %w", op, err)
+	var ok, needsRehash bool
+	if user == nil {
+		a.verifyDummyPassword(password)
+	} else {
+		ok, needsRehash, err = a.hasher.Verify(password, user.Password)
 	}
+	if err != nil || !ok {
+		a.log.Info("invalid credentials", "error", err, "known", user != nil)
 
-	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		a.log.Info("invalid credentials", err)
+		a.recordLoginEvent(ctx, log, user, client, false, false, nil, nil)
+		a.recordFailedLogin(ctx, log, lockout)
+		a.recordLoginFailure(ctx, log, input, client)
 
-		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
-	accessToken, err := jwt.NewToken(user, a.tokenTTL)
+	a.clearFailedLogins(ctx, log, user)
+
+	if needsRehash {
+		a.rehashPassword(ctx, log, user, password)
+	}
+
+	if err := statusError(user.Status.Effective(time.Now())); err != nil {
+		log.Info("account is not active", "status", user.Status.Status, "reason", user.Status.Reason)
+
+		a.recordLoginEvent(ctx, log, user, client, false, false, nil, nil)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if a.settings.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		log.Info("email not verified")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrEmailNotVerified)
+	}
+
+	trusted := a.isTrustedDevice(ctx, user, client)
+
+	signals, assessment := a.assessLogin(ctx, log, user, client, trusted)
+
+	// A source trying many accounts gets challenged even where the user's own history looks fine.
+	// Solved proof of work is that challenge
+	sourceChallenged := false
+	if abuseResponse == abuse.ResponseChallenge && !proven && assessment.Decision == risk.DecisionAllow {
+		assessment.Decision = risk.DecisionChallenge
+		sourceChallenged = true
+	}
+
+	switch assessment.Decision {
+	case risk.DecisionBlock:
+		a.recordLoginEvent(ctx, log, user, client, trusted, false, &signals, &assessment)
+
+		return nil, fmt.Errorf("%s: %w", op, ErrLoginBlocked)
+	case risk.DecisionChallenge:
+		switch {
+		case trusted:
+			log.Info("trusted device, skipping mfa challenge")
+		case sourceChallenged:
+			// Refused until the source calms down or solves the proof of work
+			a.recordLoginEvent(ctx, log, user, client, trusted, false, &signals, &assessment)
+
+			return nil, fmt.Errorf("%s: %w", op, ErrMFARequired)
+		default:
+			// There is no second factor to challenge the user with yet. Only a successful login
+			// trusts a device, refusing would keep the user out of every new device for good,
+			// so the login goes through and the user is told about it instead
+			log.Warn("risky login allowed without a second factor", "score", assessment.Score)
+
+			a.notifyRiskyLogin(ctx, log, user, client)
+		}
+	}
+
+	session, refreshToken, err := a.createSession(ctx, user, client)
+	if err != nil {
+		a.log.Error("failed to create session", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	restriction, err := a.loginRestriction(ctx, user)
 	if err != nil {
-		a.log.Error("failed to generate token", err)
+		log.Error("failed to check login restrictions", "error", err)
 
-		return "", "", fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	refreshToken, err := middlewares.UUIDGenerator()
+	accessToken, err := jwt.NewToken(user, a.tokenTTL, session.ID, passwordAuthentication(), restriction)
 	if err != nil {
-		a.log.Error("failed to generate token", err)
+		a.log.Error("failed to generate token", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.recordLoginEvent(ctx, log, user, client, trusted, true, &signals, &assessment)
+
+	if user.DeletionScheduledAt != nil {
+		a.cancelDeletion(ctx, log, user)
+	}
+
+	if restriction != "" {
+		log.Info("user logged in with a restricted token", "restriction", restriction)
+
+		return &models.LoginResult{
+			AccessToken: accessToken,
+			Restriction: restriction,
+		}, nil
+	}
 
-		return "", "", fmt.Errorf("%s: %w", op, err)
+	result := &models.LoginResult{
+		AccessToken:       accessToken,
+		RefreshToken:      refreshToken,
+		PasswordExpiresAt: a.passwordExpiryWarning(user),
+	}
+
+	if client.RememberDevice && !trusted {
+		result.DeviceToken, err = a.TrustDevice(ctx, user, client)
+		if err != nil {
+			// Remembering the device is best effort and must not fail the login
+			log.Warn("failed to remember device", "error", err)
+		}
 	}
 
 	log.Info("user logged in")
 
-	return accessToken, refreshToken.String(), nil
+	return result, nil
 }
 
 func (a *Auth) UpdateUserEmail(ctx context.Context, userId, oldEmail, newEmail string) (string, error) {
@@ -181,24 +661,41 @@ func (a *Auth) UpdateUserEmail(ctx context.Context, userId, oldEmail, newEmail s
 	err := a.userRepository.CheckUserByEmail(ctx, userId, oldEmail)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
-			a.log.Warn("user not found", err)
+			a.log.Warn("user not found", "error", err)
 
 			return "", fmt.Errorf("%s: %w", op, err)
 		}
 
-		a.log.Error("failed to get user", err)
+		a.log.Error("failed to get user", "error", err)
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = a.userRepository.UpdateEmail(ctx, userId, newEmail)
+	if status, err := a.userRepository.CheckEmailIsAvailable(ctx, newEmail); status != true || err != nil {
+		if err != nil {
+			log.Error("failed to check email availability", "error", err)
+
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		return "", fmt.Errorf("%s: %w", op, ErrEmailAlreadyTaken)
+	}
+
+	user, err := a.userRepository.GetUser(ctx, "id", userId)
 	if err != nil {
-		a.log.Error("failed to update user email", err)
+		a.log.Error("failed to get user", "error", err)
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	return "email updated successfully", nil
+	// The email only changes once the new address confirms it
+	if err := a.requestEmailChange(ctx, log, user, newEmail); err != nil {
+		log.Error("failed to request email change", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return "confirmation link sent to the new email", nil
 }
 
 func (a *Auth) UpdateUserPassword(ctx context.Context, userId, oldPassword, newPassword string) (string, error) {
@@ -211,55 +708,79 @@ func (a *Auth) UpdateUserPassword(ctx context.Context, userId, oldPassword, newP
 
 	log.Info("checking user credentials")
 
-	if len(oldPassword) < 8 || len(newPassword) < 8 {
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-	}
-
-	if oldPassword == newPassword {
+	if oldPassword == "" || newPassword == "" {
 		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
 	log.Info("picking user password from database")
 
-	password, err := a.userRepository.CheckUserByPassword(ctx, userId, oldPassword)
+	user, err := a.userRepository.GetUser(ctx, "id", userId)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
-			a.log.Warn("user not found", err)
+			a.log.Warn("user not found", "error", err)
 
-			return "", fmt.Errorf("%s: %w", op, err)
+			return "", fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
 
-		a.log.Error("failed to get user", err)
+		a.log.Error("failed to get user", "error", err)
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("comparing users password")
 
-	err = bcrypt.CompareHashAndPassword([]byte(password), []byte(oldPassword))
-	if err != nil {
-		a.log.Info("invalid credentials", err)
+	if ok, _, err := a.hasher.Verify(oldPassword, user.Password); err != nil || !ok {
+		a.log.Info("invalid credentials", "error", err)
 
 		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
+	if err := a.checkNewPassword(newPassword, user.Username, user.Email); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkPasswordReuse(ctx, user, newPassword); err != nil {
+		log.Info("password reuse rejected", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
 	log.Info("hashing new password")
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPassword, err := a.hasher.Hash(newPassword)
 	if err != nil {
-		a.log.Error("failed to hash password", err)
+		a.log.Error("failed to hash password", "error", err)
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("updating user password")
 
-	err = a.userRepository.UpdatePassword(ctx, userId, string(hashedPassword))
+	err = a.userRepository.UpdatePassword(ctx, userId, hashedPassword, a.settings.PasswordHistory)
 	if err != nil {
-		a.log.Error("failed to update user password", err)
+		a.log.Error("failed to update user password", "error", err)
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	return "password updated successfully", nil
 }
+
+// rehashPassword moves the hash of a user who just proved their password to the current
+// scheme and parameters. Failing to do so is logged and retried at the next login
+func (a *Auth) rehashPassword(ctx context.Context, log *slog.Logger, user *models.User, password string) {
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		log.Error("failed to rehash password", "error", err)
+
+		return
+	}
+
+	if err := a.userRepository.RehashPassword(ctx, user.ID.String(), user.Password, hash); err != nil {
+		log.Error("failed to save rehashed password", "error", err)
+
+		return
+	}
+
+	log.Info("password rehashed")
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"AuthService/internal/lib/password"
+	"errors"
+)
+
+// checkNewPassword applies the password policy and, when a corpus is configured, rejects
+// passwords known from breaches as one more policy violation
+func (a *Auth) checkNewPassword(newPassword string, userInfo ...string) error {
+	err := a.settings.PasswordPolicy.Check(newPassword, userInfo...)
+
+	var policyErr *password.PolicyError
+	if err != nil && !errors.As(err, &policyErr) {
+		return err
+	}
+
+	if a.breachChecker == nil {
+		return err
+	}
+
+	breached, checkErr := a.breachChecker.IsBreached(newPassword)
+	if checkErr != nil {
+		// An unreadable corpus must not lock everyone out of setting a password
+		a.log.Error("failed to check password against breaches", "error", checkErr)
+
+		return err
+	}
+
+	if !breached {
+		return err
+	}
+
+	if policyErr == nil {
+		policyErr = &password.PolicyError{}
+	}
+
+	policyErr.Violations = append(policyErr.Violations, password.Violation{
+		Rule:    password.RuleBreached,
+		Message: "has appeared in a data breach, choose another one",
+	})
+
+	return policyErr
+}
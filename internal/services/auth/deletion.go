@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/jwt"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const purgeBatchSize = 100
+
+// DeleteAccount schedules the erasure of the account the access token belongs to. The user
+// is signed out everywhere and can cancel by logging in again before the grace period ends
+func (a *Auth) DeleteAccount(ctx context.Context, accessToken string) (string, error) {
+	const op = "auth.DeleteAccount"
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		a.log.Info("invalid access token", "op", op, "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	userId := claims.UserID
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	if err := a.RequireRecentAuth(ctx, accessToken, userId); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.userRepository.GetUser(ctx, "id", userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return "", fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	deleteAt := time.Now().Add(a.settings.DeletionGracePeriod)
+
+	if err := a.userRepository.ScheduleUserDeletion(ctx, userId, deleteAt); err != nil {
+		log.Error("failed to schedule deletion", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.revokeAllSessions(ctx, log, userId); err != nil {
+		log.Error("failed to revoke sessions", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.audit(ctx, log, models.AuditAccountDeletionScheduled, userId, userId, map[string]any{
+		"delete_at": deleteAt,
+	})
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nyour account will be deleted on %s.\nIf you change your mind, just log in before then and the deletion will be cancelled.\n",
+		user.Username,
+		deleteAt.Format(time.RFC1123),
+	)
+
+	if err := a.mailer.Send(ctx, user.Email, "Your account is scheduled for deletion", body); err != nil {
+		log.Error("failed to send deletion notice", "error", err)
+	}
+
+	log.Info("account deletion scheduled", "deleteAt", deleteAt)
+
+	return fmt.Sprintf("account will be deleted on %s", deleteAt.Format(time.RFC3339)), nil
+}
+
+// cancelDeletion is called on login of a user whose account is scheduled for deletion
+func (a *Auth) cancelDeletion(ctx context.Context, log *slog.Logger, user *models.User) {
+	if err := a.userRepository.CancelUserDeletion(ctx, user.ID.String()); err != nil {
+		log.Error("failed to cancel account deletion", "error", err)
+
+		return
+	}
+
+	a.audit(ctx, log, models.AuditAccountDeletionCancelled, user.ID.String(), user.ID.String(), nil)
+
+	log.Info("account deletion cancelled by login")
+}
+
+// PurgeDeletedAccounts erases the accounts whose grace period is over. An account that fails
+// to be erased is logged and left for the next run, the others are erased all the same
+func (a *Auth) PurgeDeletedAccounts(ctx context.Context) error {
+	const op = "auth.PurgeDeletedAccounts"
+
+	log := a.log.With(slog.String("op", op))
+
+	failed := make(map[string]error)
+
+	for {
+		ids, err := a.userRepository.ListUsersDueForDeletion(ctx, time.Now(), purgeBatchSize)
+		if err != nil {
+			return errors.Join(fmt.Errorf("%s: %w", op, err), joinFailures(op, failed))
+		}
+
+		erased := 0
+		for _, userId := range ids {
+			if _, ok := failed[userId]; ok {
+				continue
+			}
+
+			tombstone := newAuditEvent(models.AuditAccountErased, userId, "", map[string]any{
+				"anonymized": a.settings.DeletionAnonymize,
+			})
+
+			if err := a.userRepository.EraseUser(ctx, userId, a.settings.DeletionAnonymize, tombstone); err != nil {
+				log.Error("failed to erase account", "userId", userId, "error", err)
+
+				failed[userId] = err
+
+				continue
+			}
+
+			erased++
+
+			log.Info("account erased", "userId", userId)
+		}
+
+		// A batch of accounts that already failed would come back as it is forever
+		if len(ids) < purgeBatchSize || erased == 0 {
+			return joinFailures(op, failed)
+		}
+	}
+}
+
+// joinFailures combines the errors of the accounts that could not be erased, nil if none failed
+func joinFailures(op string, failed map[string]error) error {
+	errs := make([]error, 0, len(failed))
+	for userId, err := range failed {
+		errs = append(errs, fmt.Errorf("%s: user %s: %w", op, userId, err))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/jwt"
+	"AuthService/internal/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"time"
+)
+
+// TrustDevice remembers the client's device for the user and returns a signed device token for it
+func (a *Auth) TrustDevice(ctx context.Context, user *models.User, client models.Client) (string, error) {
+	const op = "auth.TrustDevice"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", user.ID.String()),
+	)
+
+	if client.Fingerprint == "" {
+		return "", fmt.Errorf("%s: %w", op, ErrDeviceFingerprintRequired)
+	}
+
+	deviceID, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	device := &models.TrustedDevice{
+		ID:              deviceID,
+		UserID:          user.ID,
+		Name:            client.UserAgent,
+		FingerprintHash: hashFingerprint(client.Fingerprint),
+		CreatedAt:       now,
+		LastUsedAt:      now,
+		ExpiresAt:       now.Add(a.settings.DeviceTTL),
+	}
+
+	token, err := jwt.NewDeviceToken(user.ID, device.ID, device.FingerprintHash, a.settings.DeviceTTL)
+	if err != nil {
+		log.Error("failed to generate device token", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.deviceRepository.SaveTrustedDevice(ctx, device); err != nil {
+		log.Error("failed to save trusted device", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("device trusted", "deviceId", device.ID.String())
+
+	return token, nil
+}
+
+func (a *Auth) ListTrustedDevices(ctx context.Context, userId string) ([]models.TrustedDevice, error) {
+	const op = "auth.ListTrustedDevices"
+
+	devices, err := a.deviceRepository.ListTrustedDevices(ctx, userId)
+	if err != nil {
+		a.log.Error("failed to list trusted devices", "op", op, "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return devices, nil
+}
+
+// ForgetTrustedDevices revokes the given trusted devices of a user, or all of them when no ids are passed
+func (a *Auth) ForgetTrustedDevices(ctx context.Context, userId string, deviceIds ...string) (string, error) {
+	const op = "auth.ForgetTrustedDevices"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	deleted, err := a.deviceRepository.DeleteTrustedDevices(ctx, userId, deviceIds...)
+	if err != nil {
+		log.Error("failed to forget trusted devices", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if deleted == 0 && len(deviceIds) > 0 {
+		return "", fmt.Errorf("%s: %w", op, ErrDeviceNotFound)
+	}
+
+	log.Info("trusted devices forgotten", "count", deleted)
+
+	return "trusted devices forgotten successfully", nil
+}
+
+// isTrustedDevice reports whether the client presented a valid, unrevoked device token bound to the user
+func (a *Auth) isTrustedDevice(ctx context.Context, user *models.User, client models.Client) bool {
+	const op = "auth.isTrustedDevice"
+
+	if client.DeviceToken == "" || client.Fingerprint == "" {
+		return false
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", user.ID.String()),
+	)
+
+	userID, deviceID, fingerprintHash, err := jwt.ParseDeviceToken(client.DeviceToken)
+	if err != nil {
+		log.Info("invalid device token", "error", err)
+
+		return false
+	}
+
+	if userID != user.ID.String() || fingerprintHash != hashFingerprint(client.Fingerprint) {
+		log.Warn("device token does not match user or fingerprint")
+
+		return false
+	}
+
+	device, err := a.deviceRepository.GetTrustedDevice(ctx, userID, deviceID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrDeviceNotFound) {
+			log.Error("failed to get trusted device", "error", err)
+		}
+
+		return false
+	}
+
+	if device.FingerprintHash != fingerprintHash || time.Now().After(device.ExpiresAt) {
+		return false
+	}
+
+	if err := a.deviceRepository.TouchTrustedDevice(ctx, deviceID); err != nil {
+		log.Error("failed to touch trusted device", "error", err)
+	}
+
+	return true
+}
+
+func hashFingerprint(fingerprint string) string {
+	sum := sha256.Sum256([]byte(fingerprint))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/secret"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+// requestEmailChange records a pending change, asks the new address to confirm it
+// and gives the old address a way to revert it
+func (a *Auth) requestEmailChange(ctx context.Context, log *slog.Logger, user *models.User, newEmail string) error {
+	const op = "auth.requestEmailChange"
+
+	if err := a.emailChangeRepository.CancelPendingEmailChanges(ctx, user.ID.String()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	confirmToken, confirmTokenHash, err := secret.NewToken()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	revertToken, revertTokenHash, err := secret.NewToken()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	change := &models.EmailChange{
+		ID:               id,
+		UserID:           user.ID,
+		OldEmail:         user.Email,
+		NewEmail:         newEmail,
+		ConfirmTokenHash: confirmTokenHash,
+		RevertTokenHash:  revertTokenHash,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(a.settings.EmailChangeTTL),
+		RevertExpiresAt:  now.Add(a.settings.EmailChangeRevertTTL),
+	}
+
+	if err := a.emailChangeRepository.SaveEmailChange(ctx, change); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	confirmBody := fmt.Sprintf(
+		"Hi %s,\n\nplease confirm that this is your new email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+		user.Username,
+		a.settings.EmailChangeURL+"?token="+url.QueryEscape(confirmToken),
+		a.settings.EmailChangeTTL,
+	)
+
+	if err := a.mailer.Send(ctx, newEmail, "Confirm your new email address", confirmBody); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	noticeBody := fmt.Sprintf(
+		"Hi %s,\n\nsomeone asked to change the email of your account to %s.\nIf this wasn't you, open the link below to cancel the change and sign out all sessions:\n\n%s\n\nThe link works for %s.\n",
+		user.Username,
+		newEmail,
+		a.settings.EmailRevertURL+"?token="+url.QueryEscape(revertToken),
+		a.settings.EmailChangeRevertTTL,
+	)
+
+	if err := a.mailer.Send(ctx, user.Email, "Your email address is being changed", noticeBody); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email change requested", "changeId", change.ID.String())
+
+	return nil
+}
+
+// ConfirmEmailChange makes a pending email change active once the new address opened its link.
+// The token is only used up once the new address is known to be free
+func (a *Auth) ConfirmEmailChange(ctx context.Context, token string) (string, error) {
+	const op = "auth.ConfirmEmailChange"
+
+	tokenHash := secret.Hash(token)
+
+	change, err := a.emailChangeRepository.GetEmailChangeToConfirm(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			a.log.Info("invalid email change token", "op", op)
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		a.log.Error("failed to get email change", "op", op, "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	userId := change.UserID.String()
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	// Someone could have taken the address while the change was pending
+	if status, err := a.userRepository.CheckEmailIsAvailable(ctx, change.NewEmail); status != true || err != nil {
+		if err != nil {
+			log.Error("failed to check email availability", "error", err)
+
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		return "", fmt.Errorf("%s: %w", op, ErrEmailAlreadyTaken)
+	}
+
+	if _, err := a.emailChangeRepository.ConfirmEmailChange(ctx, tokenHash); err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Info("email change token used concurrently")
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		if errors.Is(err, storage.ErrEmailAlreadyTaken) {
+			log.Info("email taken while the change was confirmed")
+
+			return "", fmt.Errorf("%s: %w", op, ErrEmailAlreadyTaken)
+		}
+
+		log.Error("failed to confirm email change", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email change confirmed", "changeId", change.ID.String())
+
+	return "email updated successfully", nil
+}
+
+// RevertEmailChange cancels an email change from the old address. If the change was
+// already confirmed the old email is restored and every session is revoked. The token
+// is only used up once the old address is known to be free
+func (a *Auth) RevertEmailChange(ctx context.Context, token string) (string, error) {
+	const op = "auth.RevertEmailChange"
+
+	tokenHash := secret.Hash(token)
+
+	change, err := a.emailChangeRepository.GetEmailChangeToRevert(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			a.log.Info("invalid email revert token", "op", op)
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		a.log.Error("failed to get email change", "op", op, "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	userId := change.UserID.String()
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	// Once the change went through, someone else could have signed up with the old address
+	if change.ConfirmedAt != nil {
+		if status, err := a.userRepository.CheckEmailIsAvailable(ctx, change.OldEmail); status != true || err != nil {
+			if err != nil {
+				log.Error("failed to check email availability", "error", err)
+
+				return "", fmt.Errorf("%s: %w", op, err)
+			}
+
+			log.Warn("old email taken before the change was reverted", "changeId", change.ID.String())
+
+			return "", fmt.Errorf("%s: %w", op, ErrEmailAlreadyTaken)
+		}
+	}
+
+	change, err = a.emailChangeRepository.RevertEmailChange(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Info("email revert token used concurrently")
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		if errors.Is(err, storage.ErrEmailAlreadyTaken) {
+			log.Warn("old email taken while the change was reverted", "changeId", change.ID.String())
+
+			return "", fmt.Errorf("%s: %w", op, ErrEmailAlreadyTaken)
+		}
+
+		log.Error("failed to revert email change", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if change.ConfirmedAt == nil {
+		log.Info("pending email change cancelled", "changeId", change.ID.String())
+
+		return "email change cancelled", nil
+	}
+
+	log.Warn("email change reverted", "changeId", change.ID.String())
+
+	return "email change reverted, please reset your password", nil
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// verifyDummyPassword spends as much time on a password as a real check would, for
+// logins of accounts that don't exist
+func (a *Auth) verifyDummyPassword(password string) {
+	a.dummyHashOnce.Do(func() {
+		hash, err := a.hasher.Hash("dummy password for unknown accounts")
+		if err != nil {
+			a.log.Error("failed to hash dummy password", "error", err)
+
+			return
+		}
+
+		a.dummyHash = hash
+	})
+
+	_, _, _ = a.hasher.Verify(password, a.dummyHash)
+}
+
+// notifyExistingAccount tells the owner of an address that someone tried to sign up with
+// it, in place of telling the caller the address is taken
+func (a *Auth) notifyExistingAccount(ctx context.Context, log *slog.Logger, email string) {
+	user, err := a.userRepository.GetUser(ctx, "email", email)
+	if err != nil {
+		log.Error("failed to get existing account", "error", err)
+
+		return
+	}
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nsomeone tried to create an account with this email address, but it already belongs to yours. If it was you, log in instead or reset your password if you forgot it. If it was not you, ignore this email.\n",
+		user.Username,
+	)
+
+	if err := a.mailer.Send(ctx, user.Email, "You already have an account", body); err != nil {
+		log.Error("failed to send existing account notice", "error", err)
+
+		return
+	}
+
+	log.Info("registration attempted for an existing email")
+}
+
+// notifyUsernameTaken tells whoever signed up with an address that the username they chose
+// belongs to someone else, in place of telling the caller
+func (a *Auth) notifyUsernameTaken(ctx context.Context, log *slog.Logger, username, email string) {
+	body := fmt.Sprintf(
+		"Hi,\n\nsomeone tried to create an account with this email address and the username %q, but that username is already in use, so no account was created. If it was you, sign up again with another username. If it was not you, ignore this email.\n",
+		username,
+	)
+
+	if err := a.mailer.Send(ctx, email, "Your account was not created", body); err != nil {
+		log.Error("failed to send username taken notice", "error", err)
+
+		return
+	}
+
+	log.Info("registration attempted with a taken username")
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"time"
+)
+
+// passwordExpiresAt returns when the password of the user expires, nil without a maximum age
+func (a *Auth) passwordExpiresAt(user *models.User) *time.Time {
+	if a.settings.PasswordMaxAge <= 0 || user.PasswordChangedAt.IsZero() {
+		return nil
+	}
+
+	expiresAt := user.PasswordChangedAt.Add(a.settings.PasswordMaxAge)
+
+	return &expiresAt
+}
+
+// passwordExpiryWarning returns when the password expires once that is within the warning window
+func (a *Auth) passwordExpiryWarning(user *models.User) *time.Time {
+	expiresAt := a.passwordExpiresAt(user)
+	if expiresAt == nil || time.Until(*expiresAt) > a.settings.PasswordWarning {
+		return nil
+	}
+
+	return expiresAt
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/jwt"
+	"AuthService/internal/storage"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"time"
+)
+
+// ExportMyData queues an archive of everything held about the caller and returns its id.
+// The archive is collected in the background, GetDataExport reports when it is ready
+func (a *Auth) ExportMyData(ctx context.Context, accessToken, format string) (string, error) {
+	const op = "auth.ExportMyData"
+
+	if format == "" {
+		format = models.ExportFormatJSON
+	}
+
+	if format != models.ExportFormatJSON && format != models.ExportFormatZip {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidExportFormat)
+	}
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if err := a.RequireRecentAuth(ctx, accessToken, claims.UserID); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", claims.UserID),
+	)
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	export := &models.DataExport{
+		ID:        id,
+		UserID:    userID,
+		Status:    models.ExportPending,
+		Format:    format,
+		CreatedAt: time.Now(),
+	}
+
+	if err := a.dataExportRepository.SaveDataExport(ctx, export); err != nil {
+		log.Error("failed to save data export", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.audit(ctx, log, models.AuditDataExportRequested, claims.UserID, claims.UserID, map[string]any{
+		"export_id": id,
+		"format":    format,
+	})
+
+	log.Info("data export requested", "exportId", id.String())
+
+	return id.String(), nil
+}
+
+// GetDataExport returns the status of an export of the caller, with the archive once it is ready
+func (a *Auth) GetDataExport(ctx context.Context, accessToken, exportId string) (*models.DataExport, error) {
+	const op = "auth.GetDataExport"
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if err := a.requireActiveSession(ctx, claims); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	export, err := a.dataExportRepository.GetDataExport(ctx, claims.UserID, exportId)
+	if err != nil {
+		if errors.Is(err, storage.ErrExportNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrExportNotFound)
+		}
+
+		a.log.Error("failed to get data export", "op", op, "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return export, nil
+}
+
+// ProcessDataExports builds every pending export and drops the expired ones
+func (a *Auth) ProcessDataExports(ctx context.Context) error {
+	const op = "auth.ProcessDataExports"
+
+	log := a.log.With(slog.String("op", op))
+
+	if deleted, err := a.dataExportRepository.DeleteExpiredDataExports(ctx, time.Now()); err != nil {
+		log.Error("failed to delete expired data exports", "error", err)
+	} else if deleted > 0 {
+		log.Info("expired data exports deleted", "count", deleted)
+	}
+
+	for {
+		export, err := a.dataExportRepository.ClaimPendingDataExport(ctx, time.Now().Add(-a.settings.ExportLease))
+		if err != nil {
+			if errors.Is(err, storage.ErrExportNotFound) {
+				return nil
+			}
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		now := time.Now()
+		expiresAt := now.Add(a.settings.ExportTTL)
+		export.CompletedAt = &now
+		export.ExpiresAt = &expiresAt
+
+		archive, err := a.buildArchive(ctx, export)
+		if err != nil {
+			log.Error("failed to build data export", "exportId", export.ID.String(), "error", err)
+
+			export.Status = models.ExportFailed
+			export.Error = "failed to collect data"
+		} else {
+			export.Status = models.ExportReady
+			export.Archive = archive
+		}
+
+		if err := a.dataExportRepository.FinishDataExport(ctx, export); err != nil {
+			if errors.Is(err, storage.ErrExportNotFound) {
+				log.Warn("data export lease expired before it finished", "exportId", export.ID.String())
+
+				continue
+			}
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Info("data export finished", "exportId", export.ID.String(), "status", export.Status)
+	}
+}
+
+func (a *Auth) buildArchive(ctx context.Context, export *models.DataExport) ([]byte, error) {
+	data, err := a.collectPersonalData(ctx, export.UserID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Format != models.ExportFormatZip {
+		return content, nil
+	}
+
+	var buf bytes.Buffer
+
+	archive := zip.NewWriter(&buf)
+
+	file, err := archive.Create("personal-data.json")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Write(content); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// collectPersonalData gathers what every table holds about the user
+func (a *Auth) collectPersonalData(ctx context.Context, userId string) (*models.PersonalData, error) {
+	user, err := a.userRepository.GetUser(ctx, "id", userId)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &models.PersonalData{
+		GeneratedAt: time.Now(),
+		Profile: models.ExportedProfile{
+			ID:                  user.ID,
+			Username:            user.Username,
+			Email:               user.Email,
+			EmailVerifiedAt:     user.EmailVerifiedAt,
+			Role:                user.Role,
+			Status:              user.Status,
+			DeletionScheduledAt: user.DeletionScheduledAt,
+		},
+	}
+
+	// A user who never saved a profile has none to export
+	data.UserProfile, err = a.userRepository.GetProfile(ctx, userId)
+	if err != nil && !errors.Is(err, storage.ErrProfileNotFound) {
+		return nil, err
+	}
+
+	if data.UsernameHistory, err = a.userRepository.ListUsernameHistory(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if data.EmailChanges, err = a.emailChangeRepository.ListEmailChanges(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if data.Sessions, err = a.sessionRepository.ListUserSessions(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if data.TrustedDevices, err = a.deviceRepository.ListTrustedDevices(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if data.LoginEvents, err = a.loginEventRepository.ListLoginEvents(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if data.AuditEvents, err = a.auditRepository.ListAuditEvents(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if data.Consents, err = a.policyRepository.ListPolicyAcceptances(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/password"
+	"context"
+)
+
+// checkPasswordReuse rejects the current password and the ones kept in the password history,
+// as a policy violation so clients handle it like the other rules
+func (a *Auth) checkPasswordReuse(ctx context.Context, user *models.User, newPassword string) error {
+	hashes := []string{user.Password}
+
+	if a.settings.PasswordHistory > 0 {
+		history, err := a.userRepository.ListPasswordHistory(ctx, user.ID.String(), uint64(a.settings.PasswordHistory))
+		if err != nil {
+			return err
+		}
+
+		hashes = append(hashes, history...)
+	}
+
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+
+		// A hash that cannot be verified anymore cannot match either
+		if ok, _, err := a.hasher.Verify(newPassword, hash); err == nil && ok {
+			return &password.PolicyError{Violations: []password.Violation{{
+				Rule:    password.RuleReused,
+				Message: "was used recently, choose another one",
+			}}}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/secret"
+	"AuthService/internal/storage"
+	"AuthService/middlewares"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CreateInvitation lets an administrator invite people to register. An invitation bound to an
+// email is sent there, the code is returned either way together with the id to revoke it by.
+// Without expiresAt the configured TTL applies
+func (a *Auth) CreateInvitation(
+	ctx context.Context,
+	accessToken string,
+	email string,
+	maxUses int,
+	expiresAt *time.Time,
+) (string, string, error) {
+	const op = "auth.CreateInvitation"
+
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	if email != "" && !middlewares.CorrectEmailChecker(email) {
+		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidInvitation)
+	}
+
+	if maxUses == 0 {
+		maxUses = 1
+	}
+
+	if maxUses < 0 || (email != "" && maxUses != 1) {
+		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidInvitation)
+	}
+
+	if expiresAt == nil {
+		at := time.Now().Add(a.settings.InvitationTTL)
+		expiresAt = &at
+	} else if !expiresAt.After(time.Now()) {
+		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidInvitation)
+	}
+
+	admin, err := a.authorizeAdmin(ctx, accessToken)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("actorId", admin.ID.String()),
+	)
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	code, codeHash, err := secret.NewToken()
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	invitation := &models.Invitation{
+		ID:        id,
+		CodeHash:  codeHash,
+		Email:     email,
+		CreatedBy: &admin.ID,
+		MaxUses:   maxUses,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+
+	if err := a.invitationRepository.SaveInvitation(ctx, invitation); err != nil {
+		log.Error("failed to save invitation", "error", err)
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.audit(ctx, log, models.AuditInvitationCreated, "", admin.ID.String(), map[string]any{
+		"invitation_id": id,
+		"email":         email,
+		"max_uses":      maxUses,
+		"expires_at":    expiresAt,
+	})
+
+	if email != "" {
+		body := fmt.Sprintf(
+			"Hi,\n\n%s invited you to create an account. Open the link below to sign up:\n\n%s\n\nThe invitation expires on %s.\n",
+			admin.Username,
+			a.settings.InvitationURL+"?code="+url.QueryEscape(code),
+			expiresAt.Format(time.RFC1123),
+		)
+
+		// The code is handed back to the admin, who can pass it on if the email is lost
+		if err := a.mailer.Send(ctx, email, "You are invited", body); err != nil {
+			log.Error("failed to send invitation email", "error", err)
+		}
+	}
+
+	log.Info("invitation created", "invitationId", id.String())
+
+	return id.String(), code, nil
+}
+
+// RevokeInvitation makes an invitation unusable for anyone who did not register with it yet
+func (a *Auth) RevokeInvitation(ctx context.Context, accessToken, invitationId string) (string, error) {
+	const op = "auth.RevokeInvitation"
+
+	admin, err := a.authorizeAdmin(ctx, accessToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("actorId", admin.ID.String()),
+		slog.String("invitationId", invitationId),
+	)
+
+	if err := a.invitationRepository.RevokeInvitation(ctx, invitationId); err != nil {
+		if errors.Is(err, storage.ErrInvitationNotFound) {
+			return "", fmt.Errorf("%s: %w", op, ErrInvitationNotFound)
+		}
+
+		log.Error("failed to revoke invitation", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.audit(ctx, log, models.AuditInvitationRevoked, "", admin.ID.String(), map[string]any{
+		"invitation_id": invitationId,
+	})
+
+	log.Info("invitation revoked")
+
+	return "invitation revoked", nil
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/secret"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// LockoutError refuses a login until RetryAt, either because the account is locked out
+// or because the delay after its last failed login has not passed yet
+type LockoutError struct {
+	RetryAt time.Time
+	Locked  bool
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// GetLoginLockout lets an administrator see the failed logins and the lockout of an account
+func (a *Auth) GetLoginLockout(ctx context.Context, accessToken, userId string) (*models.LoginLockout, error) {
+	const op = "auth.GetLoginLockout"
+
+	if _, err := a.authorizeAdmin(ctx, accessToken); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lockout, err := a.lockoutRepository.GetLoginLockout(ctx, userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrLockoutNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrLockoutNotFound)
+		}
+
+		a.log.Error("failed to get login lockout", "op", op, "userId", userId, "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lockout, nil
+}
+
+// ClearLoginLockout lets an administrator unlock an account and forget its failed logins
+func (a *Auth) ClearLoginLockout(ctx context.Context, accessToken, userId, reason string) (string, error) {
+	const op = "auth.ClearLoginLockout"
+
+	admin, err := a.authorizeAdmin(ctx, accessToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+		slog.String("actorId", admin.ID.String()),
+	)
+
+	cleared, err := a.lockoutRepository.ClearLoginLockout(ctx, userId)
+	if err != nil {
+		log.Error("failed to clear login lockout", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !cleared {
+		return "", fmt.Errorf("%s: %w", op, ErrLockoutNotFound)
+	}
+
+	a.audit(ctx, log, models.AuditLoginLockoutCleared, userId, admin.ID.String(), map[string]any{
+		"reason": reason,
+	})
+
+	log.Info("login lockout cleared")
+
+	return "login lockout cleared", nil
+}
+
+// lockoutSubject is what failed logins are counted against: the account, or in anti-enumeration
+// mode the normalized name tried when there is no such account, so that unknown accounts get
+// locked out like real ones. Empty when failures are not counted
+func (a *Auth) lockoutSubject(user *models.User, input string) string {
+	if user != nil {
+		return user.ID.String()
+	}
+
+	if !a.settings.AntiEnumeration {
+		return ""
+	}
+
+	return "name:" + secret.Hash(strings.ToLower(strings.TrimSpace(input)))
+}
+
+// reserveLoginAttempt refuses the login of a locked out subject, or one tried again too soon
+// after a failure. Otherwise the attempt is counted as failed before the password is checked,
+// so that parallel attempts cannot all get through before any failure is recorded
+func (a *Auth) reserveLoginAttempt(ctx context.Context, subject string, user *models.User) (*models.LoginLockout, error) {
+	if a.settings.LockoutThreshold <= 0 || subject == "" {
+		return nil, nil
+	}
+
+	var userId string
+	if user != nil {
+		userId = user.ID.String()
+	}
+
+	seen, err := a.lockoutRepository.GetLoginLockout(ctx, subject)
+	if err != nil && !errors.Is(err, storage.ErrLockoutNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+	resetBefore := now.Add(-a.settings.LockoutResetAfter)
+
+	if seen != nil {
+		if seen.LockedUntil != nil && now.Before(*seen.LockedUntil) {
+			return nil, &LockoutError{RetryAt: *seen.LockedUntil, Locked: true}
+		}
+
+		if seen.FailedAttempts > 0 && !seen.LastFailedAt.Before(resetBefore) {
+			retryAt := seen.LastFailedAt.Add(a.loginDelay(seen.FailedAttempts))
+			if now.Before(retryAt) {
+				return nil, &LockoutError{RetryAt: retryAt}
+			}
+
+			// The attempt that reached the threshold is still being checked
+			if seen.FailedAttempts >= a.settings.LockoutThreshold {
+				return nil, &LockoutError{RetryAt: now.Add(a.loginDelay(1))}
+			}
+		}
+	}
+
+	lockout, reserved, err := a.lockoutRepository.ReserveLoginAttempt(ctx, subject, userId, seen, now, resetBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another attempt got in between, it has to be answered before the next one
+	if !reserved {
+		failures := 1
+		if seen != nil {
+			failures = seen.FailedAttempts + 1
+		}
+
+		return nil, &LockoutError{RetryAt: now.Add(a.loginDelay(failures))}
+	}
+
+	return lockout, nil
+}
+
+// recordFailedLogin locks the subject out once its reserved attempt turned out wrong and there
+// were too many in a row. Each lockout in a row lasts twice as long as the previous one
+func (a *Auth) recordFailedLogin(ctx context.Context, log *slog.Logger, lockout *models.LoginLockout) {
+	if lockout == nil || lockout.FailedAttempts < a.settings.LockoutThreshold {
+		return
+	}
+
+	// Unknown accounts go through the same steps, the event just has no user
+	var userId string
+	if lockout.UserID != uuid.Nil {
+		userId = lockout.UserID.String()
+	}
+
+	until := time.Now().Add(backoff(a.settings.LockoutDuration, a.settings.LockoutMaxDuration, lockout.Lockouts+1))
+
+	if err := a.lockoutRepository.LockLogins(ctx, lockout.Subject, until); err != nil {
+		log.Error("failed to lock out account", "error", err)
+
+		return
+	}
+
+	a.audit(ctx, log, models.AuditLoginLockedOut, userId, "", map[string]any{
+		"failed_attempts": lockout.FailedAttempts,
+		"lockouts":        lockout.Lockouts + 1,
+		"locked_until":    until,
+	})
+
+	log.Warn("account locked out after failed logins", "lockedUntil", until)
+}
+
+// clearFailedLogins forgets the failures of an account whose password was just proven,
+// including the attempt reserved for it
+func (a *Auth) clearFailedLogins(ctx context.Context, log *slog.Logger, user *models.User) {
+	if a.settings.LockoutThreshold <= 0 {
+		return
+	}
+
+	if _, err := a.lockoutRepository.ClearLoginLockout(ctx, user.ID.String()); err != nil {
+		log.Error("failed to clear failed logins", "error", err)
+	}
+}
+
+// PruneUnknownLogins forgets the failed logins of accounts that don't exist once they no
+// longer count towards a lockout
+func (a *Auth) PruneUnknownLogins(ctx context.Context) error {
+	const op = "auth.PruneUnknownLogins"
+
+	if !a.settings.AntiEnumeration {
+		return nil
+	}
+
+	pruned, err := a.lockoutRepository.PruneUnknownLogins(ctx, time.Now().Add(-a.settings.LockoutResetAfter))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if pruned > 0 {
+		a.log.Info("failed logins of unknown accounts pruned", "op", op, "count", pruned)
+	}
+
+	return nil
+}
+
+// loginDelay is how long to wait before trying again after the given number of failures
+func (a *Auth) loginDelay(failures int) time.Duration {
+	return backoff(a.settings.LockoutBaseDelay, a.settings.LockoutMaxDelay, failures)
+}
+
+// backoffCeiling caps every backoff, doubling without a bound would overflow into the past
+const backoffCeiling = 365 * 24 * time.Hour
+
+// backoff doubles base for every step after the first, up to max when max is set and
+// never past backoffCeiling
+func backoff(base, max time.Duration, step int) time.Duration {
+	if base <= 0 || step <= 0 {
+		return 0
+	}
+
+	delay := base
+	for i := 1; i < step; i++ {
+		if max > 0 && delay >= max {
+			break
+		}
+
+		if delay > backoffCeiling/2 {
+			delay = backoffCeiling
+			break
+		}
+
+		delay *= 2
+	}
+
+	if max > 0 && delay > max {
+		return max
+	}
+
+	return delay
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name string
+		base time.Duration
+		max  time.Duration
+		step int
+		want time.Duration
+	}{
+		{name: "no base", base: 0, max: time.Minute, step: 3, want: 0},
+		{name: "no step", base: time.Second, max: time.Minute, step: 0, want: 0},
+		{name: "first step", base: time.Second, max: time.Minute, step: 1, want: time.Second},
+		{name: "doubles", base: time.Second, max: time.Minute, step: 4, want: 8 * time.Second},
+		{name: "capped", base: time.Second, max: time.Minute, step: 10, want: time.Minute},
+		{name: "base over max", base: 2 * time.Minute, max: time.Minute, step: 1, want: time.Minute},
+		{name: "uncapped", base: time.Second, max: 0, step: 11, want: 1024 * time.Second},
+		{name: "many steps", base: time.Second, max: time.Hour, step: 1000, want: time.Hour},
+		{name: "many steps uncapped", base: time.Second, max: 0, step: 1000, want: backoffCeiling},
+		{name: "max past the ceiling", base: time.Hour, max: 200 * 365 * 24 * time.Hour, step: 100, want: backoffCeiling},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backoff(tt.base, tt.max, tt.step); got != tt.want {
+				t.Errorf("backoff(%s, %s, %d) = %s, want %s", tt.base, tt.max, tt.step, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginDelay(t *testing.T) {
+	a := &Auth{settings: Settings{LockoutBaseDelay: time.Second, LockoutMaxDelay: 30 * time.Second}}
+
+	want := []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second}
+	for failures, delay := range want {
+		if got := a.loginDelay(failures); got != delay {
+			t.Errorf("loginDelay(%d) = %s, want %s", failures, got, delay)
+		}
+	}
+}
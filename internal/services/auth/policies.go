@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/jwt"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+// PublishPolicy publishes a new version of the terms or the privacy policy. Users who did not
+// accept the latest mandatory version of a policy get a restricted token at login
+func (a *Auth) PublishPolicy(ctx context.Context, accessToken, kind, version, documentURL string, mandatory bool) (string, error) {
+	const op = "auth.PublishPolicy"
+
+	if !models.IsValidPolicyKind(kind) || version == "" {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidPolicy)
+	}
+
+	if u, err := url.Parse(documentURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidPolicy)
+	}
+
+	admin, err := a.authorizeAdmin(ctx, accessToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("actorId", admin.ID.String()),
+	)
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	document := &models.PolicyDocument{
+		ID:          id,
+		Kind:        kind,
+		Version:     version,
+		URL:         documentURL,
+		Mandatory:   mandatory,
+		PublishedAt: time.Now(),
+	}
+
+	if err := a.policyRepository.SavePolicyDocument(ctx, document); err != nil {
+		if errors.Is(err, storage.ErrPolicyExists) {
+			return "", fmt.Errorf("%s: %w", op, ErrPolicyExists)
+		}
+
+		log.Error("failed to save policy document", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.audit(ctx, log, models.AuditPolicyPublished, "", admin.ID.String(), map[string]any{
+		"document_id": id,
+		"kind":        kind,
+		"version":     version,
+		"mandatory":   mandatory,
+	})
+
+	log.Info("policy published", "kind", kind, "version", version)
+
+	return id.String(), nil
+}
+
+// PendingPolicies returns the policy versions the caller still has to accept
+func (a *Auth) PendingPolicies(ctx context.Context, accessToken string) ([]models.PolicyDocument, error) {
+	const op = "auth.PendingPolicies"
+
+	claims, err := a.authenticate(ctx, accessToken, models.RestrictionPolicyAcceptance)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	documents, err := a.policyRepository.ListPendingPolicies(ctx, claims.UserID)
+	if err != nil {
+		a.log.Error("failed to list pending policies", "op", op, "error", err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return documents, nil
+}
+
+// AcceptPolicies records that the caller accepted the given policy versions. Only the current
+// versions can be accepted. Once nothing is pending the next login gives full access again
+func (a *Auth) AcceptPolicies(ctx context.Context, accessToken string, documentIds []string, client models.Client) (string, error) {
+	const op = "auth.AcceptPolicies"
+
+	if len(documentIds) == 0 {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidPolicy)
+	}
+
+	claims, err := a.authenticate(ctx, accessToken, models.RestrictionPolicyAcceptance)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", claims.UserID),
+	)
+
+	current, err := a.policyRepository.ListCurrentPolicies(ctx)
+	if err != nil {
+		log.Error("failed to list current policies", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	accepted := make(map[string]models.PolicyDocument, len(documentIds))
+	for _, documentId := range documentIds {
+		for _, document := range current {
+			if document.ID.String() == documentId {
+				accepted[documentId] = document
+			}
+		}
+
+		if _, ok := accepted[documentId]; !ok {
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidPolicy)
+		}
+	}
+
+	if err := a.policyRepository.SavePolicyAcceptances(ctx, claims.UserID, documentIds, time.Now(), client.IP, client.UserAgent); err != nil {
+		log.Error("failed to save policy acceptances", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, document := range accepted {
+		log.Info("policy accepted", "kind", document.Kind, "version", document.Version)
+	}
+
+	return "policies accepted", nil
+}
+
+// acceptCurrentPolicies records that a new user accepted the policies in force when they registered
+func (a *Auth) acceptCurrentPolicies(ctx context.Context, userId string, client models.Client) error {
+	const op = "auth.acceptCurrentPolicies"
+
+	current, err := a.policyRepository.ListCurrentPolicies(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	documentIds := make([]string, 0, len(current))
+	for _, document := range current {
+		documentIds = append(documentIds, document.ID.String())
+	}
+
+	if err := a.policyRepository.SavePolicyAcceptances(ctx, userId, documentIds, time.Now(), client.IP, client.UserAgent); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// authenticate returns the claims of an access token with a live session that is either
+// unrestricted or carries the allowed restriction
+func (a *Auth) authenticate(ctx context.Context, accessToken, allowedRestriction string) (*jwt.AccessClaims, error) {
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Restriction != "" && claims.Restriction != allowedRestriction {
+		return nil, restrictionError(claims.Restriction)
+	}
+
+	if err := a.requireLiveSession(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/abuse"
+	"AuthService/internal/lib/pow"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ProofOfWorkError asks the client to solve Challenge, a hash with Difficulty leading zero
+// bits, and to send the solution along with the request again
+type ProofOfWorkError struct {
+	Challenge  string
+	Difficulty int
+}
+
+func (e *ProofOfWorkError) Error() string {
+	return fmt.Sprintf("%s, %d bits", ErrProofOfWorkRequired, e.Difficulty)
+}
+
+func (e *ProofOfWorkError) Unwrap() error {
+	return ErrProofOfWorkRequired
+}
+
+// loginThreat rates how suspicious a login is: the recent failed logins of its source or
+// of the lockout subject, whichever is higher. A source the abuse policy challenges is always
+// asked for work
+func (a *Auth) loginThreat(
+	ctx context.Context,
+	log *slog.Logger,
+	subject string,
+	stats models.LoginFailureStats,
+	abuseResponse abuse.Response,
+) int {
+	if !a.settings.ProofOfWork.Enabled {
+		return 0
+	}
+
+	threat := stats.IPFailures
+
+	if subject != "" {
+		lockout, err := a.lockoutRepository.GetLoginLockout(ctx, subject)
+		if err == nil && lockout.FailedAttempts > threat {
+			threat = lockout.FailedAttempts
+		}
+	}
+
+	if abuseResponse == abuse.ResponseChallenge && threat < a.settings.ProofOfWork.Threshold {
+		log.Info("abusive source, asking for proof of work")
+
+		threat = a.settings.ProofOfWork.Threshold
+	}
+
+	return threat
+}
+
+// requireProofOfWork lets the request through when the threat is low enough or the client
+// sent a fresh solution to a challenge of the scope, proven tells the latter. Otherwise a
+// new challenge is issued and returned in a ProofOfWorkError
+func (a *Auth) requireProofOfWork(
+	ctx context.Context,
+	log *slog.Logger,
+	scope string,
+	client models.Client,
+	threat int,
+) (proven bool, err error) {
+	policy := a.settings.ProofOfWork
+
+	difficulty := policy.Difficulty(threat)
+	if difficulty == 0 {
+		return false, nil
+	}
+
+	if client.ProofOfWork != "" {
+		err := a.redeemProofOfWork(ctx, scope, client, difficulty)
+		if err == nil {
+			return true, nil
+		}
+
+		log.Info("proof of work refused", "error", err)
+	}
+
+	challenge, err := policy.Issue(scope, client.IP, difficulty)
+	if err != nil {
+		return false, err
+	}
+
+	log.Info("proof of work required", slog.Int("threat", threat), slog.Int("difficulty", difficulty))
+
+	return false, &ProofOfWorkError{Challenge: challenge, Difficulty: difficulty}
+}
+
+// redeemProofOfWork checks the solution the client sent and spends its challenge, so it
+// cannot be replayed for another request
+func (a *Auth) redeemProofOfWork(ctx context.Context, scope string, client models.Client, difficulty int) error {
+	challenge, err := a.settings.ProofOfWork.Verify(client.ProofOfWork, scope, client.IP, difficulty)
+	if err != nil {
+		return err
+	}
+
+	redeemed, err := a.challengeRepository.RedeemChallenge(ctx, challenge.Nonce, challenge.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	if !redeemed {
+		return fmt.Errorf("%w: already spent", pow.ErrInvalidChallenge)
+	}
+
+	return nil
+}
+
+// PruneChallenges forgets the spent challenges that expired, they are refused anyway
+func (a *Auth) PruneChallenges(ctx context.Context) error {
+	const op = "auth.PruneChallenges"
+
+	if !a.settings.ProofOfWork.Enabled {
+		return nil
+	}
+
+	pruned, err := a.challengeRepository.PruneChallenges(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if pruned > 0 {
+		a.log.Info("spent challenges pruned", "op", op, "count", pruned)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/secret"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+const requestPasswordResetMessage = "if an account with this email exists, a password reset link has been sent"
+
+// RequestPasswordReset emails a single-use reset link. It answers the same way whether
+// or not the address belongs to an account so it cannot be used to discover accounts:
+// the link is issued and sent in the background, and a failure there is only logged
+func (a *Auth) RequestPasswordReset(ctx context.Context, email string) (string, error) {
+	const op = "auth.RequestPasswordReset"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	user, err := a.userRepository.GetUser(ctx, "email", email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("password reset requested for unknown email")
+		} else {
+			log.Error("failed to get user", "error", err)
+		}
+
+		return requestPasswordResetMessage, nil
+	}
+
+	// The request may be cancelled as soon as it is answered, the email has to go out anyway
+	go a.sendPasswordReset(context.WithoutCancel(ctx), log, user)
+
+	return requestPasswordResetMessage, nil
+}
+
+// sendPasswordReset issues a reset token for the user and emails the link that carries it
+func (a *Auth) sendPasswordReset(ctx context.Context, log *slog.Logger, user *models.User) {
+	link, err := a.passwordResetLink(ctx, user.ID.String(), a.settings.ResetTTL)
+	if err != nil {
+		log.Error("failed to issue reset token", "error", err)
+
+		return
+	}
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nsomeone asked to reset the password of your account. If it was you, open the link below:\n\n%s\n\nThe link expires in %s and can be used once. If it was not you, ignore this email.\n",
+		user.Username,
+		link,
+		a.settings.ResetTTL,
+	)
+
+	if err := a.mailer.Send(ctx, user.Email, "Reset your password", body); err != nil {
+		log.Error("failed to send reset email", "error", err)
+
+		return
+	}
+
+	log.Info("password reset requested")
+}
+
+// ResetPassword sets the new password of the user a reset token was issued to and signs them
+// out everywhere. The token is only used up together with the password change, a password
+// the policy rejects leaves it valid for another try
+func (a *Auth) ResetPassword(ctx context.Context, token, newPassword string) (string, error) {
+	const op = "auth.ResetPassword"
+
+	tokenHash := secret.Hash(token)
+
+	userId, err := a.passwordResetRepository.GetPasswordResetToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			a.log.Info("invalid reset token", "op", op)
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		a.log.Error("failed to get reset token", "op", op, "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	user, err := a.userRepository.GetUser(ctx, "id", userId)
+	if err != nil {
+		log.Error("failed to get user", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkNewPassword(newPassword, user.Username, user.Email); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkPasswordReuse(ctx, user, newPassword); err != nil {
+		log.Info("password reuse rejected", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	hashedPassword, err := a.hasher.Hash(newPassword)
+	if err != nil {
+		log.Error("failed to hash password", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := a.passwordResetRepository.ResetPassword(ctx, tokenHash, hashedPassword, a.settings.PasswordHistory); err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Info("reset token used concurrently")
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to reset password", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset, sessions and trusted devices revoked")
+
+	return "password reset successfully", nil
+}
+
+// passwordResetLink stores a single-use reset token and returns the link that carries it
+func (a *Auth) passwordResetLink(ctx context.Context, userId string, ttl time.Duration) (string, error) {
+	token, tokenHash, err := secret.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := a.passwordResetRepository.SavePasswordResetToken(ctx, userId, tokenHash, time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+
+	return a.settings.ResetURL + "?token=" + url.QueryEscape(token), nil
+}
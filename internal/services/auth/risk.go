@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/risk"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// assessLogin scores a login attempt with a correct password against the login history of the user
+func (a *Auth) assessLogin(ctx context.Context, log *slog.Logger, user *models.User, client models.Client, trusted bool) (risk.Signals, risk.Assessment) {
+	signals := risk.Signals{NewDevice: !trusted}
+
+	stats, err := a.loginEventRepository.GetLoginStats(
+		ctx,
+		user.ID.String(),
+		client.IP,
+		risk.Network(client.IP),
+		time.Now().Add(-a.settings.RiskFailureWindow),
+	)
+	if err != nil {
+		// Missing history must not lock users out, score the attempt on what is known
+		log.Error("failed to get login stats", "error", err)
+	} else {
+		signals.FirstLogin = stats.Successes == 0
+		signals.NewIP = !stats.KnownIP
+		signals.NewNetwork = !stats.KnownNetwork
+		signals.RecentFailures = stats.RecentFailures
+		if !stats.LastSuccessAt.IsZero() {
+			signals.SinceLastLogin = time.Since(stats.LastSuccessAt)
+		}
+	}
+
+	assessment := a.settings.RiskPolicy.Evaluate(signals)
+
+	log.Info("login risk assessed",
+		slog.Int("score", assessment.Score),
+		slog.String("decision", string(assessment.Decision)),
+		slog.Bool("newDevice", signals.NewDevice),
+		slog.Bool("newIp", signals.NewIP),
+		slog.Bool("newNetwork", signals.NewNetwork),
+		slog.Int("recentFailures", signals.RecentFailures),
+		slog.Duration("sinceLastLogin", signals.SinceLastLogin),
+		slog.Bool("firstLogin", signals.FirstLogin),
+	)
+
+	return signals, assessment
+}
+
+// recordLoginEvent stores a login attempt so later attempts can be scored against it
+func (a *Auth) recordLoginEvent(
+	ctx context.Context,
+	log *slog.Logger,
+	user *models.User,
+	client models.Client,
+	trusted bool,
+	success bool,
+	signals *risk.Signals,
+	assessment *risk.Assessment,
+) {
+	event := &models.LoginEvent{
+		IP:            client.IP,
+		Network:       risk.Network(client.IP),
+		UserAgent:     client.UserAgent,
+		DeviceTrusted: trusted,
+		Success:       success,
+		Signals:       []byte("{}"),
+		CreatedAt:     time.Now(),
+	}
+
+	// A failed login of an unknown account is recorded without a user
+	if user != nil {
+		event.UserID = user.ID
+	}
+
+	if assessment != nil {
+		event.RiskScore = assessment.Score
+		event.RiskDecision = string(assessment.Decision)
+	}
+
+	if signals != nil {
+		if raw, err := json.Marshal(signals); err == nil {
+			event.Signals = raw
+		}
+	}
+
+	if err := a.loginEventRepository.SaveLoginEvent(ctx, event); err != nil {
+		log.Error("failed to save login event", "error", err)
+	}
+}
+
+// notifyRiskyLogin tells the user about a login that looked risky and was let through, so that
+// they can secure their account if it was not them
+func (a *Auth) notifyRiskyLogin(ctx context.Context, log *slog.Logger, user *models.User, client models.Client) {
+	body := fmt.Sprintf(
+		"Hi %s,\n\nyour account was just signed in to from a new device or location:\n\naddress: %s\nbrowser: %s\n\nIf it was you, you can ignore this email. If it was not you, reset your password right away.\n",
+		user.Username,
+		client.IP,
+		client.UserAgent,
+	)
+
+	if err := a.mailer.Send(ctx, user.Email, "New sign-in to your account", body); err != nil {
+		log.Error("failed to send risky login notice", "error", err)
+
+		return
+	}
+
+	log.Info("risky login notice sent")
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/secret"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"time"
+)
+
+// createSession starts a session for the user and returns it with the refresh token bound to it
+func (a *Auth) createSession(ctx context.Context, user *models.User, client models.Client) (*models.Session, string, error) {
+	const op = "auth.createSession"
+
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	refreshToken, refreshTokenHash, err := secret.NewToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		RefreshTokenHash: refreshTokenHash,
+		IP:               client.IP,
+		UserAgent:        client.UserAgent,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(a.settings.RefreshTokenTTL),
+	}
+
+	if err := a.sessionRepository.SaveSession(ctx, session); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, refreshToken, nil
+}
+
+// revokeAllSessions signs the user out everywhere: sessions, refresh tokens and trusted devices
+func (a *Auth) revokeAllSessions(ctx context.Context, log *slog.Logger, userId string) error {
+	const op = "auth.revokeAllSessions"
+
+	sessions, err := a.sessionRepository.RevokeUserSessions(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	devices, err := a.deviceRepository.DeleteTrustedDevices(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("sessions revoked", "sessions", sessions, "devices", devices)
+
+	return nil
+}
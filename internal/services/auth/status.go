@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SetAccountStatus suspends, locks or bans an account, or makes it active again. Any change
+// away from active revokes the live sessions of the user
+func (a *Auth) SetAccountStatus(
+	ctx context.Context,
+	accessToken string,
+	userId string,
+	status string,
+	reason string,
+	expiresAt *time.Time,
+) (string, error) {
+	const op = "auth.SetAccountStatus"
+
+	if !models.IsValidStatus(status) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidStatus)
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidStatus)
+	}
+
+	admin, err := a.authorizeAdmin(ctx, accessToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+		slog.String("actorId", admin.ID.String()),
+	)
+
+	now := time.Now()
+	accountStatus := models.AccountStatus{
+		Status:    status,
+		Reason:    reason,
+		ActorID:   &admin.ID,
+		ExpiresAt: expiresAt,
+		ChangedAt: &now,
+	}
+
+	if status == models.StatusActive {
+		accountStatus.ExpiresAt = nil
+	}
+
+	if err := a.userRepository.SetUserStatus(ctx, userId, accountStatus); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return "", fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to set account status", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if status != models.StatusActive {
+		if err := a.revokeAllSessions(ctx, log, userId); err != nil {
+			log.Error("failed to revoke sessions", "error", err)
+
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	a.audit(ctx, log, models.AuditAccountStatusChanged, userId, admin.ID.String(), map[string]any{
+		"status":     status,
+		"reason":     reason,
+		"expires_at": accountStatus.ExpiresAt,
+	})
+
+	log.Info("account status changed", "status", status)
+
+	return "account status updated successfully", nil
+}
+
+// ClearAccountStatus makes an account active again
+func (a *Auth) ClearAccountStatus(ctx context.Context, accessToken, userId, reason string) (string, error) {
+	return a.SetAccountStatus(ctx, accessToken, userId, models.StatusActive, reason, nil)
+}
+
+// statusError returns the error a non-active account is refused with
+func statusError(status string) error {
+	switch status {
+	case models.StatusSuspended:
+		return ErrAccountSuspended
+	case models.StatusLocked:
+		return ErrAccountLocked
+	case models.StatusBanned:
+		return ErrAccountBanned
+	default:
+		return nil
+	}
+}
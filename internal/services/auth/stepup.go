@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/jwt"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// StepUp re-verifies the password of the token holder and issues an access token with a fresh
+// auth_time. Wrong passwords count towards the lockout of the account and the failed logins of
+// the source like those of a login, a stolen token is no way around them
+func (a *Auth) StepUp(ctx context.Context, accessToken, password string, client models.Client) (string, error) {
+	const op = "auth.StepUp"
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		a.log.Info("invalid access token", "op", op, "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", claims.UserID),
+	)
+
+	if err := a.requireActiveSession(ctx, claims); err != nil {
+		log.Info("session is not active", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.userRepository.GetUser(ctx, "id", claims.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", "error", err)
+
+			return "", fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := a.checkLoginAbuse(ctx, log, a.sourceFailureStats(ctx, log, client)); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	lockout, err := a.reserveLoginAttempt(ctx, user.ID.String(), user)
+	if err != nil {
+		log.Info("step-up refused after failed attempts", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if ok, _, err := a.hasher.Verify(password, user.Password); err != nil || !ok {
+		log.Info("invalid credentials", "error", err)
+
+		a.recordFailedLogin(ctx, log, lockout)
+		a.recordLoginFailure(ctx, log, user.Username, client)
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	a.clearFailedLogins(ctx, log, user)
+
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	token, err := jwt.NewToken(user, a.tokenTTL, sessionID, passwordAuthentication(), "")
+	if err != nil {
+		log.Error("failed to generate token", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user stepped up")
+
+	return token, nil
+}
+
+// RequireRecentAuth checks that the access token belongs to the user and comes from
+// an authentication that is recent and strong enough for a sensitive operation
+func (a *Auth) RequireRecentAuth(ctx context.Context, accessToken, userId string) error {
+	const op = "auth.RequireRecentAuth"
+
+	if err := a.requireRecentAuth(ctx, accessToken, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RequirePasswordChangeAuth is RequireRecentAuth for changing the password, the one
+// operation a token restricted to a password change or by an expired password is good for
+func (a *Auth) RequirePasswordChangeAuth(ctx context.Context, accessToken, userId string) error {
+	const op = "auth.RequirePasswordChangeAuth"
+
+	err := a.requireRecentAuth(ctx, accessToken, userId,
+		models.RestrictionPasswordChange,
+		models.RestrictionPasswordExpired,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// requireRecentAuth accepts unrestricted tokens and tokens with one of the allowed restrictions
+func (a *Auth) requireRecentAuth(ctx context.Context, accessToken, userId string, allowedRestrictions ...string) error {
+	const op = "auth.requireRecentAuth"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		log.Info("invalid access token", "error", err)
+
+		return ErrInvalidToken
+	}
+
+	if claims.UserID != userId {
+		log.Warn("access token issued for another user")
+
+		return ErrInvalidToken
+	}
+
+	if claims.Restriction != "" && !slices.Contains(allowedRestrictions, claims.Restriction) {
+		return restrictionError(claims.Restriction)
+	}
+
+	if err := a.requireLiveSession(ctx, claims); err != nil {
+		log.Info("session is not active", "error", err)
+
+		return err
+	}
+
+	authn := claims.Authentication
+
+	if !models.ACRSatisfies(authn.Level, a.settings.StepUpACR) || time.Since(authn.Time) > a.settings.StepUpMaxAge {
+		log.Info("step-up authentication required",
+			slog.String("acr", authn.Level),
+			slog.Time("authTime", authn.Time),
+		)
+
+		return ErrStepUpRequired
+	}
+
+	return nil
+}
+
+// Authenticate returns the claims of an access token whose session is still active
+func (a *Auth) Authenticate(ctx context.Context, accessToken string) (*jwt.AccessClaims, error) {
+	const op = "auth.Authenticate"
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if err := a.requireActiveSession(ctx, claims); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return claims, nil
+}
+
+// requireActiveSession rejects restricted access tokens and ones whose session has been revoked
+func (a *Auth) requireActiveSession(ctx context.Context, claims *jwt.AccessClaims) error {
+	if claims.Restriction != "" {
+		return restrictionError(claims.Restriction)
+	}
+
+	return a.requireLiveSession(ctx, claims)
+}
+
+// requireLiveSession rejects access tokens whose session has been revoked
+func (a *Auth) requireLiveSession(ctx context.Context, claims *jwt.AccessClaims) error {
+	if claims.SessionID == "" {
+		return ErrInvalidToken
+	}
+
+	active, err := a.sessionRepository.IsSessionActive(ctx, claims.SessionID)
+	if err != nil {
+		return err
+	}
+
+	if !active {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// loginRestriction returns what the user has to do before their login gives full access
+func (a *Auth) loginRestriction(ctx context.Context, user *models.User) (string, error) {
+	if user.MustChangePassword {
+		return models.RestrictionPasswordChange, nil
+	}
+
+	if expiresAt := a.passwordExpiresAt(user); expiresAt != nil && !time.Now().Before(*expiresAt) {
+		return models.RestrictionPasswordExpired, nil
+	}
+
+	pending, err := a.policyRepository.ListPendingPolicies(ctx, user.ID.String())
+	if err != nil {
+		return "", err
+	}
+
+	if len(pending) > 0 {
+		return models.RestrictionPolicyAcceptance, nil
+	}
+
+	return "", nil
+}
+
+// restrictionError tells the holder of a restricted token what they have to do first
+func restrictionError(restriction string) error {
+	switch restriction {
+	case models.RestrictionPasswordChange:
+		return ErrPasswordChangeRequired
+	case models.RestrictionPasswordExpired:
+		return ErrPasswordExpired
+	case models.RestrictionPolicyAcceptance:
+		return ErrPolicyAcceptanceRequired
+	default:
+		return ErrInvalidToken
+	}
+}
+
+func passwordAuthentication() models.Authentication {
+	return models.Authentication{
+		Time:    time.Now(),
+		Methods: []string{models.AMRPassword},
+		Level:   models.ACRPassword,
+	}
+}
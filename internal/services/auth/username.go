@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/jwt"
+	"AuthService/middlewares"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ChangeUsername renames the caller. The previous username is kept in the history and stays
+// reserved so that nobody can pick it up to impersonate them
+func (a *Auth) ChangeUsername(ctx context.Context, accessToken, username string) (string, error) {
+	const op = "auth.ChangeUsername"
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if err := a.RequireRecentAuth(ctx, accessToken, claims.UserID); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", claims.UserID),
+	)
+
+	if !middlewares.CheckUsername(username) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidUsername)
+	}
+
+	user, err := a.userRepository.GetUser(ctx, "id", claims.UserID)
+	if err != nil {
+		log.Error("failed to get user", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Username == username {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidUsername)
+	}
+
+	history, err := a.userRepository.ListUsernameHistory(ctx, claims.UserID)
+	if err != nil {
+		log.Error("failed to list username history", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+
+	if len(history) > 0 {
+		if next := history[0].ChangedAt.Add(a.settings.UsernameChangeInterval); now.Before(next) {
+			log.Info("username changed too recently", slog.Time("nextChangeAt", next))
+
+			return "", fmt.Errorf("%s: %w", op, ErrUsernameChangeTooSoon)
+		}
+	}
+
+	available, err := a.userRepository.CheckUsernameIsAvailableFor(ctx, claims.UserID, username)
+	if err != nil {
+		log.Error("failed to check username availability", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !available {
+		return "", fmt.Errorf("%s: %w", op, ErrUsernameTaken)
+	}
+
+	if err := a.userRepository.ChangeUsername(ctx, claims.UserID, username, now.Add(a.settings.UsernameReservation)); err != nil {
+		log.Error("failed to change username", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.audit(ctx, log, models.AuditUsernameChanged, claims.UserID, claims.UserID, map[string]any{
+		"from": user.Username,
+		"to":   username,
+	})
+
+	log.Info("username changed")
+
+	return "username changed", nil
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/lib/jwt"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+)
+
+const resendVerificationMessage = "if the account exists and is not verified yet, a verification email has been sent"
+
+// VerifyEmail consumes a verification token and marks the email it was sent to as verified
+func (a *Auth) VerifyEmail(ctx context.Context, token string) (string, error) {
+	const op = "auth.VerifyEmail"
+
+	userId, email, err := jwt.ParseEmailToken(token, jwt.PurposeEmailVerification)
+	if err != nil {
+		a.log.Info("invalid verification token", "op", op, "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("userId", userId),
+	)
+
+	// The token is only valid while the address it was sent to is still the user's email
+	if err := a.userRepository.MarkEmailVerified(ctx, userId, email); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("verification token for an outdated email", "error", err)
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to mark email verified", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email verified")
+
+	return "email verified successfully", nil
+}
+
+// ResendVerification sends a new verification email. It answers the same way for
+// unknown and already verified addresses so it cannot be used to discover accounts:
+// the email is sent in the background, and a failure there is only logged
+func (a *Auth) ResendVerification(ctx context.Context, email string) (string, error) {
+	const op = "auth.ResendVerification"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	user, err := a.userRepository.GetUser(ctx, "email", email)
+	if err != nil {
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			log.Error("failed to get user", "error", err)
+		}
+
+		return resendVerificationMessage, nil
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return resendVerificationMessage, nil
+	}
+
+	go func(ctx context.Context) {
+		if err := a.sendVerificationEmail(ctx, user); err != nil {
+			log.Error("failed to send verification email", "error", err)
+		}
+	}(context.WithoutCancel(ctx))
+
+	return resendVerificationMessage, nil
+}
+
+func (a *Auth) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	const op = "auth.sendVerificationEmail"
+
+	token, err := jwt.NewEmailToken(jwt.PurposeEmailVerification, user.ID, user.Email, a.settings.VerificationTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	link := a.settings.VerificationURL + "?token=" + url.QueryEscape(token)
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nplease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+		user.Username,
+		link,
+		a.settings.VerificationTTL,
+	)
+
+	if err := a.mailer.Send(ctx, user.Email, "Confirm your email address", body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
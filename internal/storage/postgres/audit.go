@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	const op = "storage.Postgres.SaveAuditEvent"
+
+	sql, args, err := auditEventInsert(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func auditEventInsert(event *models.AuditEvent) (string, []interface{}, error) {
+	return squirrel.Insert("audit_events").
+		Columns("user_id", "actor_id", "action", "details", "created_at").
+		Values(event.UserID, event.ActorID, event.Action, event.Details, event.CreatedAt).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+}
+
+func (s *Storage) ListAuditEvents(ctx context.Context, userId string) ([]models.AuditEvent, error) {
+	const op = "storage.Postgres.ListAuditEvents"
+
+	sql, args, err := squirrel.Select("id", "user_id", "actor_id", "action", "details", "created_at").
+		From("audit_events").
+		Where(squirrel.Eq{"user_id": userId}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var event models.AuditEvent
+		var userID, actorID pgtype.UUID
+
+		if err := rows.Scan(&event.ID, &userID, &actorID, &event.Action, &event.Details, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if userID.Valid {
+			id := uuid.UUID(userID.Bytes)
+			event.UserID = &id
+		}
+
+		if actorID.Valid {
+			id := uuid.UUID(actorID.Bytes)
+			event.ActorID = &id
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"time"
+)
+
+// RedeemChallenge spends a proof of work challenge, it reports false if it was spent before.
+// The nonce is kept until the challenge expires, after that it is refused anyway
+func (s *Storage) RedeemChallenge(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	const op = "storage.Postgres.RedeemChallenge"
+
+	sql, args, err := squirrel.Insert("pow_redemptions").
+		Columns("nonce", "expires_at").
+		Values(nonce, expiresAt).
+		Suffix("ON CONFLICT (nonce) DO NOTHING").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// PruneChallenges forgets the spent challenges that expired before the given time
+func (s *Storage) PruneChallenges(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.Postgres.PruneChallenges"
+
+	sql, args, err := squirrel.Delete("pow_redemptions").
+		Where(squirrel.Lt{"expires_at": before}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,230 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// userDataTables hold rows that only make sense while their user exists
+var userDataTables = []string{
+	"sessions",
+	"trusted_devices",
+	"login_events",
+	"password_reset_tokens",
+	"email_changes",
+	"data_exports",
+	"user_profiles",
+	"username_history",
+	"policy_acceptances",
+	"password_history",
+	"login_lockouts",
+}
+
+// auditPersonalKeys are the audit event details that carry personal data, such as the
+// usernames of a rename or the address an invitation went to
+var auditPersonalKeys = []string{"from", "to", "email", "reason"}
+
+func (s *Storage) ScheduleUserDeletion(ctx context.Context, userId string, at time.Time) error {
+	const op = "storage.Postgres.ScheduleUserDeletion"
+
+	sql, args, err := squirrel.Update("users").
+		SetMap(squirrel.Eq{"deletion_scheduled_at": at}).
+		Where(squirrel.Eq{"id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) CancelUserDeletion(ctx context.Context, userId string) error {
+	const op = "storage.Postgres.CancelUserDeletion"
+
+	sql, args, err := squirrel.Update("users").
+		SetMap(squirrel.Eq{"deletion_scheduled_at": nil}).
+		Where(squirrel.Eq{"id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListUsersDueForDeletion returns the ids of users whose grace period ended before the given time
+func (s *Storage) ListUsersDueForDeletion(ctx context.Context, before time.Time, limit uint64) ([]string, error) {
+	const op = "storage.Postgres.ListUsersDueForDeletion"
+
+	sql, args, err := squirrel.Select("id").
+		From("users").
+		Where(squirrel.NotEq{"deletion_scheduled_at": nil}).
+		Where(squirrel.LtOrEq{"deletion_scheduled_at": before}).
+		OrderBy("deletion_scheduled_at").
+		Limit(limit).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// EraseUser removes everything held about a user and writes the tombstone in the same transaction.
+// With anonymize the user row stays behind, stripped of personal data and credentials. The audit
+// trail of the user is kept, stripped of personal data as well, and so are the invitations sent
+// to the user, revoked and without the address they were sent to
+func (s *Storage) EraseUser(ctx context.Context, userId string, anonymize bool, tombstone *models.AuditEvent) error {
+	const op = "storage.Postgres.EraseUser"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, err := squirrel.Select("email", "invitation_id").
+		From("users").
+		Where(squirrel.Eq{"id": userId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var email string
+	var invitationId *string
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&email, &invitationId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql, args, err = squirrel.Update("audit_events").
+		Set("details", squirrel.Expr("details - ?::text[]", auditPersonalKeys)).
+		Where(squirrel.Or{
+			squirrel.Eq{"user_id": userId},
+			squirrel.Expr("details->>'email' = ?", email),
+		}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	invitations := squirrel.Or{squirrel.Expr("LOWER(email) = LOWER(?)", email)}
+	if invitationId != nil {
+		invitations = append(invitations, squirrel.Eq{"id": *invitationId})
+	}
+
+	// An invitation restricted to the address would be open to anyone without it
+	sql, args, err = squirrel.Update("invitations").
+		Set("email", "").
+		Set("revoked_at", squirrel.Expr("CASE WHEN email <> '' THEN COALESCE(revoked_at, ?) ELSE revoked_at END", time.Now())).
+		Where(invitations).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, table := range userDataTables {
+		sql, args, err := squirrel.Delete(table).
+			Where(squirrel.Eq{"user_id": userId}).
+			PlaceholderFormat(squirrel.Dollar).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if anonymize {
+		sql, args, err = squirrel.Update("users").
+			SetMap(squirrel.Eq{
+				"username":              "deleted-" + userId,
+				"email":                 "deleted-" + userId + "@invalid",
+				"password":              "",
+				"email_verified_at":     nil,
+				"deletion_scheduled_at": nil,
+				"updated_at":            time.Now(),
+			}).
+			Where(squirrel.Eq{"id": userId}).
+			PlaceholderFormat(squirrel.Dollar).
+			ToSql()
+	} else {
+		sql, args, err = squirrel.Delete("users").
+			Where(squirrel.Eq{"id": userId}).
+			PlaceholderFormat(squirrel.Dollar).
+			ToSql()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql, args, err = auditEventInsert(tombstone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+func (s *Storage) SaveTrustedDevice(ctx context.Context, device *models.TrustedDevice) error {
+	const op = "storage.Postgres.SaveTrustedDevice"
+
+	sql, args, err := squirrel.Insert("trusted_devices").
+		Columns("id", "user_id", "name", "fingerprint_hash", "created_at", "last_used_at", "expires_at").
+		Values(device.ID, device.UserID, device.Name, device.FingerprintHash, device.CreatedAt, device.LastUsedAt, device.ExpiresAt).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetTrustedDevice fetches a device that belongs to the given user
+func (s *Storage) GetTrustedDevice(ctx context.Context, userId, deviceId string) (*models.TrustedDevice, error) {
+	const op = "storage.Postgres.GetTrustedDevice"
+
+	sql, args, err := squirrel.Select("id", "user_id", "name", "fingerprint_hash", "created_at", "last_used_at", "expires_at").
+		From("trusted_devices").
+		Where(squirrel.Eq{"id": deviceId, "user_id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var device models.TrustedDevice
+	err = s.db.QueryRow(ctx, sql, args...).Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Name,
+		&device.FingerprintHash,
+		&device.CreatedAt,
+		&device.LastUsedAt,
+		&device.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrDeviceNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &device, nil
+}
+
+func (s *Storage) ListTrustedDevices(ctx context.Context, userId string) ([]models.TrustedDevice, error) {
+	const op = "storage.Postgres.ListTrustedDevices"
+
+	sql, args, err := squirrel.Select("id", "user_id", "name", "fingerprint_hash", "created_at", "last_used_at", "expires_at").
+		From("trusted_devices").
+		Where(squirrel.Eq{"user_id": userId}).
+		Where(squirrel.Gt{"expires_at": time.Now()}).
+		OrderBy("last_used_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var devices []models.TrustedDevice
+	for rows.Next() {
+		var device models.TrustedDevice
+		if err := rows.Scan(
+			&device.ID,
+			&device.UserID,
+			&device.Name,
+			&device.FingerprintHash,
+			&device.CreatedAt,
+			&device.LastUsedAt,
+			&device.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		devices = append(devices, device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return devices, nil
+}
+
+func (s *Storage) TouchTrustedDevice(ctx context.Context, deviceId string) error {
+	const op = "storage.Postgres.TouchTrustedDevice"
+
+	sql, args, err := squirrel.Update("trusted_devices").
+		SetMap(squirrel.Eq{"last_used_at": time.Now()}).
+		Where(squirrel.Eq{"id": deviceId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteTrustedDevices removes the given devices of a user, or all of them when no ids are passed
+func (s *Storage) DeleteTrustedDevices(ctx context.Context, userId string, deviceIds ...string) (int64, error) {
+	const op = "storage.Postgres.DeleteTrustedDevices"
+
+	where := squirrel.Eq{"user_id": userId}
+	if len(deviceIds) > 0 {
+		where["id"] = deviceIds
+	}
+
+	sql, args, err := squirrel.Delete("trusted_devices").
+		Where(where).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,268 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"strings"
+	"time"
+)
+
+var emailChangeColumns = []string{
+	"id", "user_id", "old_email", "new_email", "created_at", "expires_at", "revert_expires_at", "confirmed_at", "reverted_at",
+}
+
+func (s *Storage) SaveEmailChange(ctx context.Context, change *models.EmailChange) error {
+	const op = "storage.Postgres.SaveEmailChange"
+
+	sql, args, err := squirrel.Insert("email_changes").
+		Columns("id", "user_id", "old_email", "new_email", "confirm_token_hash", "revert_token_hash", "created_at", "expires_at", "revert_expires_at").
+		Values(change.ID, change.UserID, change.OldEmail, change.NewEmail, change.ConfirmTokenHash, change.RevertTokenHash, change.CreatedAt, change.ExpiresAt, change.RevertExpiresAt).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetEmailChangeToConfirm returns the pending change of a live confirm token without using it up
+func (s *Storage) GetEmailChangeToConfirm(ctx context.Context, confirmTokenHash string) (*models.EmailChange, error) {
+	const op = "storage.Postgres.GetEmailChangeToConfirm"
+
+	sql, args, err := squirrel.Select(emailChangeColumns...).
+		From("email_changes").
+		Where(squirrel.Eq{"confirm_token_hash": confirmTokenHash, "confirmed_at": nil, "reverted_at": nil}).
+		Where(squirrel.Gt{"expires_at": time.Now()}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	change, err := scanEmailChange(s.db.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrTokenNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return change, nil
+}
+
+// GetEmailChangeToRevert returns the change of a live revert token without using it up
+func (s *Storage) GetEmailChangeToRevert(ctx context.Context, revertTokenHash string) (*models.EmailChange, error) {
+	const op = "storage.Postgres.GetEmailChangeToRevert"
+
+	sql, args, err := squirrel.Select(emailChangeColumns...).
+		From("email_changes").
+		Where(squirrel.Eq{"revert_token_hash": revertTokenHash, "reverted_at": nil}).
+		Where(squirrel.Gt{"revert_expires_at": time.Now()}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	change, err := scanEmailChange(s.db.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrTokenNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return change, nil
+}
+
+// ConfirmEmailChange marks a pending change as confirmed if its token is still live and
+// switches the user to the new address, all or nothing. It fails with storage.ErrEmailAlreadyTaken
+// if another account has the address, the token stays live then
+func (s *Storage) ConfirmEmailChange(ctx context.Context, confirmTokenHash string) (*models.EmailChange, error) {
+	const op = "storage.Postgres.ConfirmEmailChange"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	now := time.Now()
+
+	sql, args, err := squirrel.Update("email_changes").
+		SetMap(squirrel.Eq{"confirmed_at": now}).
+		Where(squirrel.Eq{"confirm_token_hash": confirmTokenHash, "confirmed_at": nil, "reverted_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Suffix("RETURNING " + strings.Join(emailChangeColumns, ", ")).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	change, err := scanEmailChange(tx.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrTokenNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := setVerifiedEmail(ctx, tx, change.UserID.String(), change.NewEmail); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return change, nil
+}
+
+// RevertEmailChange marks a change as reverted if its revert window is still open and returns it.
+// A change that was already confirmed is undone in the same transaction: the user gets the old
+// address back and is signed out everywhere. It fails with storage.ErrEmailAlreadyTaken if another
+// account has the old address, the token stays live then
+func (s *Storage) RevertEmailChange(ctx context.Context, revertTokenHash string) (*models.EmailChange, error) {
+	const op = "storage.Postgres.RevertEmailChange"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	now := time.Now()
+
+	sql, args, err := squirrel.Update("email_changes").
+		SetMap(squirrel.Eq{"reverted_at": now}).
+		Where(squirrel.Eq{"revert_token_hash": revertTokenHash, "reverted_at": nil}).
+		Where(squirrel.Gt{"revert_expires_at": now}).
+		Suffix("RETURNING " + strings.Join(emailChangeColumns, ", ")).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	change, err := scanEmailChange(tx.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrTokenNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if change.ConfirmedAt != nil {
+		if err := setVerifiedEmail(ctx, tx, change.UserID.String(), change.OldEmail); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := revokeSessions(ctx, tx, change.UserID.String()); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return change, nil
+}
+
+// CancelPendingEmailChanges drops the unconfirmed changes of a user so only the latest request stays valid
+func (s *Storage) CancelPendingEmailChanges(ctx context.Context, userId string) error {
+	const op = "storage.Postgres.CancelPendingEmailChanges"
+
+	sql, args, err := squirrel.Delete("email_changes").
+		Where(squirrel.Eq{"user_id": userId, "confirmed_at": nil, "reverted_at": nil}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanEmailChange(row pgx.Row) (*models.EmailChange, error) {
+	var change models.EmailChange
+	var confirmedAt, revertedAt pgtype.Timestamp
+
+	err := row.Scan(
+		&change.ID,
+		&change.UserID,
+		&change.OldEmail,
+		&change.NewEmail,
+		&change.CreatedAt,
+		&change.ExpiresAt,
+		&change.RevertExpiresAt,
+		&confirmedAt,
+		&revertedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmedAt.Valid {
+		change.ConfirmedAt = &confirmedAt.Time
+	}
+
+	if revertedAt.Valid {
+		change.RevertedAt = &revertedAt.Time
+	}
+
+	return &change, nil
+}
+
+func (s *Storage) ListEmailChanges(ctx context.Context, userId string) ([]models.EmailChange, error) {
+	const op = "storage.Postgres.ListEmailChanges"
+
+	sql, args, err := squirrel.Select(emailChangeColumns...).
+		From("email_changes").
+		Where(squirrel.Eq{"user_id": userId}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var changes []models.EmailChange
+	for rows.Next() {
+		change, err := scanEmailChange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		changes = append(changes, *change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+func (s *Storage) SaveDataExport(ctx context.Context, export *models.DataExport) error {
+	const op = "storage.Postgres.SaveDataExport"
+
+	sql, args, err := squirrel.Insert("data_exports").
+		Columns("id", "user_id", "status", "format", "created_at").
+		Values(export.ID, export.UserID, export.Status, export.Format, export.CreatedAt).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetDataExport fetches an export of the given user together with its archive
+func (s *Storage) GetDataExport(ctx context.Context, userId, exportId string) (*models.DataExport, error) {
+	const op = "storage.Postgres.GetDataExport"
+
+	sql, args, err := squirrel.Select("id", "user_id", "status", "format", "archive", "error", "created_at", "completed_at", "expires_at").
+		From("data_exports").
+		Where(squirrel.Eq{"id": exportId, "user_id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var export models.DataExport
+	var completedAt, expiresAt pgtype.Timestamp
+
+	err = s.db.QueryRow(ctx, sql, args...).Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.Format,
+		&export.Archive,
+		&export.Error,
+		&export.CreatedAt,
+		&completedAt,
+		&expiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrExportNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+
+	return &export, nil
+}
+
+// ClaimPendingDataExport marks the oldest pending export as running and returns it. Concurrent
+// workers skip exports claimed by each other. A running export claimed before leaseBefore was
+// left behind by a worker that stopped, it is claimed again
+func (s *Storage) ClaimPendingDataExport(ctx context.Context, leaseBefore time.Time) (*models.DataExport, error) {
+	const op = "storage.Postgres.ClaimPendingDataExport"
+
+	next := squirrel.Select("id").
+		From("data_exports").
+		Where(squirrel.Or{
+			squirrel.Eq{"status": models.ExportPending},
+			squirrel.And{
+				squirrel.Eq{"status": models.ExportRunning},
+				squirrel.Lt{"claimed_at": leaseBefore},
+			},
+		}).
+		OrderBy("created_at").
+		Limit(1).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	sql, args, err := squirrel.Update("data_exports").
+		SetMap(squirrel.Eq{"status": models.ExportRunning, "claimed_at": time.Now()}).
+		Where(squirrel.Expr("id = (?)", next)).
+		Suffix("RETURNING id, user_id, status, format, created_at, claimed_at").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var export models.DataExport
+	err = s.db.QueryRow(ctx, sql, args...).Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.Format,
+		&export.CreatedAt,
+		&export.ClaimedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrExportNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &export, nil
+}
+
+// FinishDataExport stores the outcome of a running export. It fails with storage.ErrExportNotFound
+// when the export was claimed again in the meantime, the outcome then belongs to the new claim
+func (s *Storage) FinishDataExport(ctx context.Context, export *models.DataExport) error {
+	const op = "storage.Postgres.FinishDataExport"
+
+	sql, args, err := squirrel.Update("data_exports").
+		SetMap(squirrel.Eq{
+			"status":       export.Status,
+			"archive":      export.Archive,
+			"error":        export.Error,
+			"completed_at": export.CompletedAt,
+			"expires_at":   export.ExpiresAt,
+		}).
+		Where(squirrel.Eq{"id": export.ID, "status": models.ExportRunning, "claimed_at": export.ClaimedAt}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrExportNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteExpiredDataExports(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.Postgres.DeleteExpiredDataExports"
+
+	sql, args, err := squirrel.Delete("data_exports").
+		Where(squirrel.LtOrEq{"expires_at": before}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+func (s *Storage) SaveInvitation(ctx context.Context, invitation *models.Invitation) error {
+	const op = "storage.Postgres.SaveInvitation"
+
+	sql, args, err := squirrel.Insert("invitations").
+		Columns("id", "code_hash", "email", "created_by", "max_uses", "created_at", "expires_at").
+		Values(
+			invitation.ID,
+			invitation.CodeHash,
+			invitation.Email,
+			invitation.CreatedBy,
+			invitation.MaxUses,
+			invitation.CreatedAt,
+			invitation.ExpiresAt,
+		).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeInvitation(ctx context.Context, invitationId string) error {
+	const op = "storage.Postgres.RevokeInvitation"
+
+	sql, args, err := squirrel.Update("invitations").
+		SetMap(squirrel.Eq{"revoked_at": time.Now()}).
+		Where(squirrel.Eq{"id": invitationId, "revoked_at": nil}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrInvitationNotFound)
+	}
+
+	return nil
+}
+
+// SaveInvitedUser uses up one use of a live invitation and creates the user in the same
+// transaction, recording who invited them. It returns the invitation that was used
+func (s *Storage) SaveInvitedUser(
+	ctx context.Context,
+	id uuid.UUID,
+	username, email string,
+	passHash []byte,
+	codeHash string,
+) (*models.Invitation, error) {
+	const op = "storage.Postgres.SaveInvitedUser"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	now := time.Now()
+
+	sql, args, err := squirrel.Update("invitations").
+		Set("uses", squirrel.Expr("uses + 1")).
+		Where(squirrel.Eq{"code_hash": codeHash, "revoked_at": nil}).
+		Where("uses < max_uses").
+		Where(squirrel.Or{squirrel.Eq{"expires_at": nil}, squirrel.Gt{"expires_at": now}}).
+		Where(squirrel.Or{squirrel.Eq{"email": ""}, squirrel.Expr("LOWER(email) = LOWER(?)", email)}).
+		Suffix("RETURNING id, email, created_by, max_uses, uses, created_at, expires_at").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	invitation := models.Invitation{CodeHash: codeHash}
+	err = tx.QueryRow(ctx, sql, args...).Scan(
+		&invitation.ID,
+		&invitation.Email,
+		&invitation.CreatedBy,
+		&invitation.MaxUses,
+		&invitation.Uses,
+		&invitation.CreatedAt,
+		&invitation.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrInvitationNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql, args, err = squirrel.Insert("users").
+		Columns("id", "username", "email", "password", "created_at", "invited_by", "invitation_id").
+		Values(id, username, email, passHash, now, invitation.CreatedBy, invitation.ID).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrUserAlreadyExists)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &invitation, nil
+}
@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"strings"
+	"time"
+)
+
+var lockoutColumns = []string{"subject", "user_id", "failed_attempts", "last_failed_at", "locked_until", "lockouts"}
+
+// GetLoginLockout returns the failed logins of a subject, the id of an account or the
+// normalized name of one that does not exist
+func (s *Storage) GetLoginLockout(ctx context.Context, subject string) (*models.LoginLockout, error) {
+	const op = "storage.Postgres.GetLoginLockout"
+
+	sql, args, err := squirrel.Select(lockoutColumns...).
+		From("login_lockouts").
+		Where(squirrel.Eq{"subject": subject}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lockout, err := scanLockout(s.db.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrLockoutNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lockout, nil
+}
+
+// ReserveLoginAttempt counts a login attempt as failed before its password is checked, the
+// count is cleared if it turns out right. The attempt is only counted if the lockout is still
+// as seen, nil when there was none, so that concurrent attempts cannot all pass the checks made
+// on it: reserved is false when another attempt came first. Failures and lockouts older than
+// resetBefore are forgotten, the count starts over. userId is empty for unknown accounts
+func (s *Storage) ReserveLoginAttempt(
+	ctx context.Context,
+	subject, userId string,
+	seen *models.LoginLockout,
+	at, resetBefore time.Time,
+) (*models.LoginLockout, bool, error) {
+	const op = "storage.Postgres.ReserveLoginAttempt"
+
+	var query squirrel.Sqlizer
+	if seen == nil {
+		var user any
+		if userId != "" {
+			user = userId
+		}
+
+		query = squirrel.Insert("login_lockouts").
+			Columns("subject", "user_id", "failed_attempts", "last_failed_at").
+			Values(subject, user, 1, at).
+			Suffix("ON CONFLICT (subject) DO NOTHING").
+			Suffix("RETURNING " + strings.Join(lockoutColumns, ", ")).
+			PlaceholderFormat(squirrel.Dollar)
+	} else {
+		query = squirrel.Update("login_lockouts").
+			Set("failed_attempts", squirrel.Expr("CASE WHEN last_failed_at < ? THEN 1 ELSE failed_attempts + 1 END", resetBefore)).
+			Set("lockouts", squirrel.Expr("CASE WHEN last_failed_at < ? THEN 0 ELSE lockouts END", resetBefore)).
+			Set("last_failed_at", at).
+			Where(squirrel.Eq{
+				"subject":         subject,
+				"failed_attempts": seen.FailedAttempts,
+				"last_failed_at":  seen.LastFailedAt,
+				"lockouts":        seen.Lockouts,
+			}).
+			Suffix("RETURNING " + strings.Join(lockoutColumns, ", ")).
+			PlaceholderFormat(squirrel.Dollar)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lockout, err := scanLockout(s.db.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lockout, true, nil
+}
+
+// LockLogins locks the logins of a subject until the given time. The failures that led
+// to it are spent, the lockout counts towards the next one
+func (s *Storage) LockLogins(ctx context.Context, subject string, until time.Time) error {
+	const op = "storage.Postgres.LockLogins"
+
+	sql, args, err := squirrel.Update("login_lockouts").
+		Set("locked_until", until).
+		Set("failed_attempts", 0).
+		Set("lockouts", squirrel.Expr("lockouts + 1")).
+		Where(squirrel.Eq{"subject": subject}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.db.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ClearLoginLockout forgets the failed logins and the lockout of a subject
+func (s *Storage) ClearLoginLockout(ctx context.Context, subject string) (bool, error) {
+	const op = "storage.Postgres.ClearLoginLockout"
+
+	sql, args, err := squirrel.Delete("login_lockouts").
+		Where(squirrel.Eq{"subject": subject}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// PruneUnknownLogins forgets the failed logins of accounts that don't exist, the lockouts
+// with no failure since the given time and no lockout left, and their login events
+func (s *Storage) PruneUnknownLogins(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.Postgres.PruneUnknownLogins"
+
+	lockouts, lockoutArgs, err := squirrel.Delete("login_lockouts").
+		Where(squirrel.Eq{"user_id": nil}).
+		Where(squirrel.Lt{"last_failed_at": before}).
+		Where(squirrel.Or{squirrel.Eq{"locked_until": nil}, squirrel.Lt{"locked_until": time.Now()}}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events, eventArgs, err := squirrel.Delete("login_events").
+		Where(squirrel.Eq{"user_id": nil}).
+		Where(squirrel.Lt{"created_at": before}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var pruned int64
+	for _, query := range []struct {
+		sql  string
+		args []any
+	}{
+		{lockouts, lockoutArgs},
+		{events, eventArgs},
+	} {
+		tag, err := s.db.Exec(ctx, query.sql, query.args...)
+		if err != nil {
+			return pruned, fmt.Errorf("%s: %w", op, err)
+		}
+
+		pruned += tag.RowsAffected()
+	}
+
+	return pruned, nil
+}
+
+func scanLockout(row pgx.Row) (*models.LoginLockout, error) {
+	var lockout models.LoginLockout
+	var userID pgtype.UUID
+	var lockedUntil pgtype.Timestamp
+
+	err := row.Scan(
+		&lockout.Subject,
+		&userID,
+		&lockout.FailedAttempts,
+		&lockout.LastFailedAt,
+		&lockedUntil,
+		&lockout.Lockouts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		lockout.UserID = userID.Bytes
+	}
+	if lockedUntil.Valid {
+		lockout.LockedUntil = &lockedUntil.Time
+	}
+
+	return &lockout, nil
+}
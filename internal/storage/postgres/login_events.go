@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+func (s *Storage) SaveLoginEvent(ctx context.Context, event *models.LoginEvent) error {
+	const op = "storage.Postgres.SaveLoginEvent"
+
+	// Failed logins of unknown accounts are kept without a user
+	var userID any
+	if event.UserID != uuid.Nil {
+		userID = event.UserID
+	}
+
+	sql, args, err := squirrel.Insert("login_events").
+		Columns("user_id", "ip", "network", "user_agent", "device_trusted", "success", "risk_score", "risk_decision", "signals", "created_at").
+		Values(userID, event.IP, event.Network, event.UserAgent, event.DeviceTrusted, event.Success, event.RiskScore, event.RiskDecision, event.Signals, event.CreatedAt).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetLoginStats summarises the login history of a user for the given ip and network
+func (s *Storage) GetLoginStats(ctx context.Context, userId, ip, network string, failuresSince time.Time) (*models.LoginStats, error) {
+	const op = "storage.Postgres.GetLoginStats"
+
+	sql, args, err := squirrel.Select().
+		Column("COUNT(*) FILTER (WHERE success)").
+		Column(squirrel.Expr("COALESCE(BOOL_OR(success AND ip = ?), FALSE)", ip)).
+		Column(squirrel.Expr("COALESCE(BOOL_OR(success AND network = ?), FALSE)", network)).
+		Column(squirrel.Expr("COUNT(*) FILTER (WHERE NOT success AND created_at > ?)", failuresSince)).
+		Column("MAX(created_at) FILTER (WHERE success)").
+		From("login_events").
+		Where(squirrel.Eq{"user_id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var stats models.LoginStats
+	var lastSuccessAt pgtype.Timestamp
+
+	err = s.db.QueryRow(ctx, sql, args...).Scan(
+		&stats.Successes,
+		&stats.KnownIP,
+		&stats.KnownNetwork,
+		&stats.RecentFailures,
+		&lastSuccessAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if lastSuccessAt.Valid {
+		stats.LastSuccessAt = lastSuccessAt.Time
+	}
+
+	return &stats, nil
+}
+
+func (s *Storage) ListLoginEvents(ctx context.Context, userId string) ([]models.LoginEvent, error) {
+	const op = "storage.Postgres.ListLoginEvents"
+
+	sql, args, err := squirrel.Select("user_id", "ip", "network", "user_agent", "device_trusted", "success", "risk_score", "risk_decision", "signals", "created_at").
+		From("login_events").
+		Where(squirrel.Eq{"user_id": userId}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.LoginEvent
+	for rows.Next() {
+		var event models.LoginEvent
+		if err := rows.Scan(
+			&event.UserID,
+			&event.IP,
+			&event.Network,
+			&event.UserAgent,
+			&event.DeviceTrusted,
+			&event.Success,
+			&event.RiskScore,
+			&event.RiskDecision,
+			&event.Signals,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+func (s *Storage) SaveLoginFailure(ctx context.Context, failure *models.LoginFailure) error {
+	const op = "storage.Postgres.SaveLoginFailure"
+
+	sql, args, err := squirrel.Insert("login_failures").
+		Columns("ip", "network", "username_hash", "created_at").
+		Values(failure.IP, failure.Network, failure.UsernameHash, failure.CreatedAt).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetLoginFailureStats counts the failed logins since the given time from an ip and its network
+func (s *Storage) GetLoginFailureStats(ctx context.Context, ip, network string, since time.Time) (*models.LoginFailureStats, error) {
+	const op = "storage.Postgres.GetLoginFailureStats"
+
+	sql, args, err := squirrel.Select().
+		Column(squirrel.Expr("COUNT(*) FILTER (WHERE ip = ?)", ip)).
+		Column("COUNT(*)").
+		Column(squirrel.Expr("COUNT(DISTINCT username_hash) FILTER (WHERE ip = ?)", ip)).
+		From("login_failures").
+		Where(squirrel.Eq{"network": network}).
+		Where(squirrel.Gt{"created_at": since}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var stats models.LoginFailureStats
+
+	err = s.db.QueryRow(ctx, sql, args...).Scan(&stats.IPFailures, &stats.NetworkFailures, &stats.DistinctUsernames)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &stats, nil
+}
+
+// PruneLoginFailures deletes the failed logins older than the given time
+func (s *Storage) PruneLoginFailures(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.Postgres.PruneLoginFailures"
+
+	sql, args, err := squirrel.Delete("login_failures").
+		Where(squirrel.Lt{"created_at": before}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// ListPasswordHistory returns the hashes of the previous passwords of a user, newest first
+func (s *Storage) ListPasswordHistory(ctx context.Context, userId string, limit uint64) ([]string, error) {
+	const op = "storage.Postgres.ListPasswordHistory"
+
+	sql, args, err := squirrel.Select("password_hash").
+		From("password_history").
+		Where(squirrel.Eq{"user_id": userId}).
+		OrderBy("id DESC").
+		Limit(limit).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hashes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hashes, nil
+}
+
+// prunePasswordHistory keeps only the newest hashes of a user
+func prunePasswordHistory(ctx context.Context, tx pgx.Tx, userId string, keep int) error {
+	newest := squirrel.Select("id").
+		From("password_history").
+		Where(squirrel.Eq{"user_id": userId}).
+		OrderBy("id DESC").
+		Limit(uint64(keep))
+
+	sql, args, err := squirrel.Delete("password_history").
+		Where(squirrel.Eq{"user_id": userId}).
+		Where(squirrel.Expr("id NOT IN (?)", newest)).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+
+	return err
+}
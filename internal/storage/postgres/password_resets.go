@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+func (s *Storage) SavePasswordResetToken(ctx context.Context, userId, tokenHash string, expiresAt time.Time) error {
+	const op = "storage.Postgres.SavePasswordResetToken"
+
+	sql, args, err := squirrel.Insert("password_reset_tokens").
+		Columns("token_hash", "user_id", "created_at", "expires_at").
+		Values(tokenHash, userId, time.Now(), expiresAt).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetPasswordResetToken returns the user of a live reset token without using it up
+func (s *Storage) GetPasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
+	const op = "storage.Postgres.GetPasswordResetToken"
+
+	sql, args, err := squirrel.Select("user_id").
+		From("password_reset_tokens").
+		Where(squirrel.Eq{"token_hash": tokenHash, "used_at": nil}).
+		Where(squirrel.Gt{"expires_at": time.Now()}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var userId string
+	err = s.db.QueryRow(ctx, sql, args...).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrTokenNotFound)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userId, nil
+}
+
+// ResetPassword uses up a live reset token together with every other token of its user, sets
+// the new password of that user and signs them out everywhere, all or nothing. It fails with
+// storage.ErrTokenNotFound if the token was used in the meantime
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash, password string, historySize int) (string, error) {
+	const op = "storage.Postgres.ResetPassword"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	now := time.Now()
+
+	sql, args, err := squirrel.Update("password_reset_tokens").
+		SetMap(squirrel.Eq{"used_at": now}).
+		Where(squirrel.Eq{"token_hash": tokenHash, "used_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Suffix("RETURNING user_id").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var userId string
+	err = tx.QueryRow(ctx, sql, args...).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrTokenNotFound)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql, args, err = squirrel.Update("password_reset_tokens").
+		SetMap(squirrel.Eq{"used_at": now}).
+		Where(squirrel.Eq{"user_id": userId, "used_at": nil}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := setPassword(ctx, tx, userId, password, historySize); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := revokeSessions(ctx, tx, userId); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userId, nil
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"time"
+)
+
+var policyDocumentColumns = []string{"id", "kind", "version", "url", "mandatory", "published_at"}
+
+func (s *Storage) SavePolicyDocument(ctx context.Context, document *models.PolicyDocument) error {
+	const op = "storage.Postgres.SavePolicyDocument"
+
+	sql, args, err := squirrel.Insert("policy_documents").
+		Columns(policyDocumentColumns...).
+		Values(
+			document.ID,
+			document.Kind,
+			document.Version,
+			document.URL,
+			document.Mandatory,
+			document.PublishedAt,
+		).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrPolicyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListCurrentPolicies returns the latest published version of every kind of policy
+func (s *Storage) ListCurrentPolicies(ctx context.Context) ([]models.PolicyDocument, error) {
+	const op = "storage.Postgres.ListCurrentPolicies"
+
+	sql, args, err := squirrel.Select(policyDocumentColumns...).
+		Options("DISTINCT ON (kind)").
+		From("policy_documents").
+		Where(squirrel.LtOrEq{"published_at": time.Now()}).
+		OrderBy("kind", "published_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	documents, err := s.queryPolicyDocuments(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return documents, nil
+}
+
+// ListPendingPolicies returns the latest mandatory version of every kind of policy
+// that the user has not accepted yet
+func (s *Storage) ListPendingPolicies(ctx context.Context, userId string) ([]models.PolicyDocument, error) {
+	const op = "storage.Postgres.ListPendingPolicies"
+
+	latest := squirrel.Select(policyDocumentColumns...).
+		Options("DISTINCT ON (kind)").
+		From("policy_documents").
+		Where(squirrel.Eq{"mandatory": true}).
+		Where(squirrel.LtOrEq{"published_at": time.Now()}).
+		OrderBy("kind", "published_at DESC")
+
+	sql, args, err := squirrel.Select(policyDocumentColumns...).
+		FromSelect(latest, "latest").
+		Where(
+			"NOT EXISTS (SELECT 1 FROM policy_acceptances pa WHERE pa.document_id = latest.id AND pa.user_id = ?)",
+			userId,
+		).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	documents, err := s.queryPolicyDocuments(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return documents, nil
+}
+
+func (s *Storage) SavePolicyAcceptances(
+	ctx context.Context,
+	userId string,
+	documentIds []string,
+	acceptedAt time.Time,
+	ip, userAgent string,
+) error {
+	const op = "storage.Postgres.SavePolicyAcceptances"
+
+	if len(documentIds) == 0 {
+		return nil
+	}
+
+	query := squirrel.Insert("policy_acceptances").
+		Columns("user_id", "document_id", "accepted_at", "ip", "user_agent")
+
+	for _, documentId := range documentIds {
+		query = query.Values(userId, documentId, acceptedAt, ip, userAgent)
+	}
+
+	sql, args, err := query.
+		Suffix("ON CONFLICT (user_id, document_id) DO NOTHING").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ListPolicyAcceptances(ctx context.Context, userId string) ([]models.PolicyAcceptance, error) {
+	const op = "storage.Postgres.ListPolicyAcceptances"
+
+	sql, args, err := squirrel.Select("pa.document_id", "pd.kind", "pd.version", "pa.accepted_at", "pa.ip", "pa.user_agent").
+		From("policy_acceptances pa").
+		Join("policy_documents pd ON pd.id = pa.document_id").
+		Where(squirrel.Eq{"pa.user_id": userId}).
+		OrderBy("pa.accepted_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var acceptances []models.PolicyAcceptance
+	for rows.Next() {
+		var acceptance models.PolicyAcceptance
+		err := rows.Scan(
+			&acceptance.DocumentID,
+			&acceptance.Kind,
+			&acceptance.Version,
+			&acceptance.AcceptedAt,
+			&acceptance.IP,
+			&acceptance.UserAgent,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		acceptances = append(acceptances, acceptance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return acceptances, nil
+}
+
+func (s *Storage) queryPolicyDocuments(ctx context.Context, sql string, args []interface{}) ([]models.PolicyDocument, error) {
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var documents []models.PolicyDocument
+	for rows.Next() {
+		var document models.PolicyDocument
+		err := rows.Scan(
+			&document.ID,
+			&document.Kind,
+			&document.Version,
+			&document.URL,
+			&document.Mandatory,
+			&document.PublishedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+
+	return documents, rows.Err()
+}
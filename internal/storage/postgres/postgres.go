@@ -10,9 +10,9 @@ import (
 	_ "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/lib/pq"
 	"time"
 )
 
@@ -33,6 +33,13 @@ func NewPostgres(conn string) (*Storage, error) {
 	}, nil
 }
 
+// isUniqueViolation reports whether a statement failed on a unique constraint
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 func (s *Storage) SaveUser(ctx context.Context, id uuid.UUID, username, email string, passHash []byte) (string, error) {
 	const op = "storage.Postgres.SaveUser"
 
@@ -47,8 +54,38 @@ func (s *Storage) SaveUser(ctx context.Context, id uuid.UUID, username, email st
 
 	_, err = s.db.Exec(ctx, sql, args...)
 	if err != nil {
-		var pgErr *pq.Error
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		if isUniqueViolation(err) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrUserAlreadyExists)
+		}
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id.String(), nil
+}
+
+// SaveCreatedUser stores a user created by an administrator, who has to choose their own password
+func (s *Storage) SaveCreatedUser(
+	ctx context.Context,
+	id uuid.UUID,
+	username, email string,
+	passHash []byte,
+	createdBy uuid.UUID,
+) (string, error) {
+	const op = "storage.Postgres.SaveCreatedUser"
+
+	sql, args, err := squirrel.Insert("users").
+		Columns("id", "username", "email", "password", "created_at", "must_change_password", "created_by").
+		Values(id, username, email, passHash, time.Now(), true, createdBy).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
 			return "", fmt.Errorf("%s: %w", op, storage.ErrUserAlreadyExists)
 		}
 
@@ -62,10 +99,26 @@ func (s *Storage) SaveUser(ctx context.Context, id uuid.UUID, username, email st
 func (s *Storage) GetUser(ctx context.Context, inputType, input string) (*models.User, error) {
 	const op = "storage.Postgres.GetUser"
 
-	var pgUUID pgtype.UUID
+	var pgUUID, statusActorID pgtype.UUID
+	var emailVerifiedAt, deletionScheduledAt, statusExpiresAt, statusChangedAt pgtype.Timestamp
 	var user models.User
 
-	sql, args, err := squirrel.Select("id", "username", "email", "password").
+	sql, args, err := squirrel.Select(
+		"id",
+		"username",
+		"email",
+		"password",
+		"email_verified_at",
+		"deletion_scheduled_at",
+		"role",
+		"status",
+		"status_reason",
+		"status_actor_id",
+		"status_expires_at",
+		"status_changed_at",
+		"must_change_password",
+		"password_changed_at",
+	).
 		From("users").
 		Where(squirrel.Eq{inputType: input}).
 		PlaceholderFormat(squirrel.Dollar).
@@ -74,7 +127,22 @@ func (s *Storage) GetUser(ctx context.Context, inputType, input string) (*models
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = s.db.QueryRow(ctx, sql, args...).Scan(&pgUUID, &user.Username, &user.Email, &user.Password)
+	err = s.db.QueryRow(ctx, sql, args...).Scan(
+		&pgUUID,
+		&user.Username,
+		&user.Email,
+		&user.Password,
+		&emailVerifiedAt,
+		&deletionScheduledAt,
+		&user.Role,
+		&user.Status.Status,
+		&user.Status.Reason,
+		&statusActorID,
+		&statusExpiresAt,
+		&statusChangedAt,
+		&user.MustChangePassword,
+		&user.PasswordChangedAt,
+	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -83,13 +151,72 @@ func (s *Storage) GetUser(ctx context.Context, inputType, input string) (*models
 	}
 
 	user.ID = pgUUID.Bytes
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if deletionScheduledAt.Valid {
+		user.DeletionScheduledAt = &deletionScheduledAt.Time
+	}
+	if statusActorID.Valid {
+		actorID := uuid.UUID(statusActorID.Bytes)
+		user.Status.ActorID = &actorID
+	}
+	if statusExpiresAt.Valid {
+		user.Status.ExpiresAt = &statusExpiresAt.Time
+	}
+	if statusChangedAt.Valid {
+		user.Status.ChangedAt = &statusChangedAt.Time
+	}
 
 	return &user, nil
 }
 
+// SetUserStatus replaces the account status of a user
+func (s *Storage) SetUserStatus(ctx context.Context, userId string, status models.AccountStatus) error {
+	const op = "storage.Postgres.SetUserStatus"
+
+	sql, args, err := squirrel.Update("users").
+		SetMap(squirrel.Eq{
+			"status":            status.Status,
+			"status_reason":     status.Reason,
+			"status_actor_id":   status.ActorID,
+			"status_expires_at": status.ExpiresAt,
+			"status_changed_at": status.ChangedAt,
+		}).
+		Where(squirrel.Eq{"id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
 func (s *Storage) CheckUsernameIsAvailable(ctx context.Context, input string) (bool, error) {
 	const op = "storage.CheckLoginIsAvailable"
 
+	return s.checkUsernameIsAvailable(ctx, op, input, "")
+}
+
+// CheckUsernameIsAvailableFor tells whether the user may take the username. A username the
+// user gave up is still reserved for them, they can take it back
+func (s *Storage) CheckUsernameIsAvailableFor(ctx context.Context, userId, input string) (bool, error) {
+	const op = "storage.CheckUsernameIsAvailableFor"
+
+	return s.checkUsernameIsAvailable(ctx, op, input, userId)
+}
+
+func (s *Storage) checkUsernameIsAvailable(ctx context.Context, op, input, userId string) (bool, error) {
 	sql, args, err := squirrel.Select("id").
 		From("users").
 		Where(squirrel.Eq{"username": input}).
@@ -101,14 +228,21 @@ func (s *Storage) CheckUsernameIsAvailable(ctx context.Context, input string) (b
 
 	var id uuid.UUID
 	err = s.db.QueryRow(ctx, sql, args...).Scan(&id)
+	if err == nil {
+		return false, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A username given up recently stays reserved for its previous owner
+	reserved, err := s.isUsernameReserved(ctx, input, userId)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return true, nil // Логин доступен
-		}
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return false, nil
+	return !reserved, nil // Логин доступен
 }
 
 func (s *Storage) CheckEmailIsAvailable(ctx context.Context, email string) (bool, error) {
@@ -163,7 +297,7 @@ func (s *Storage) UpdateEmail(ctx context.Context, userId, email string) error {
 	const op = "storage.Postgres.UpdateEmail"
 
 	sql, args, err := squirrel.Update("users").
-		SetMap(squirrel.Eq{"email": email}).
+		SetMap(squirrel.Eq{"email": email, "email_verified_at": nil}).
 		SetMap(squirrel.Eq{"updated_at": time.Now()}).
 		Where(squirrel.Eq{"id": userId}).
 		PlaceholderFormat(squirrel.Dollar).
@@ -174,43 +308,187 @@ func (s *Storage) UpdateEmail(ctx context.Context, userId, email string) error {
 
 	_, err = s.db.Exec(ctx, sql, args...)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrEmailAlreadyTaken)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MarkEmailVerified marks the email of a user as verified if it is still the current one
+func (s *Storage) MarkEmailVerified(ctx context.Context, userId, email string) error {
+	const op = "storage.Postgres.MarkEmailVerified"
+
+	sql, args, err := squirrel.Update("users").
+		SetMap(squirrel.Eq{"email_verified_at": time.Now()}).
+		Where(squirrel.Eq{"id": userId, "email": email}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// UpdatePassword sets a new password hash and moves the previous one into the password history,
+// which is pruned to the given number of hashes
+func (s *Storage) UpdatePassword(ctx context.Context, userId, password string, historySize int) error {
+	const op = "storage.Postgres.UpdatePassword"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := setPassword(ctx, tx, userId, password, historySize); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Storage) CheckUserByPassword(ctx context.Context, userId, password string) (string, error) {
-	const op = "storage.Postgres.CheckUserByPassword"
+// setVerifiedEmail switches the email of a user to an address that proved it belongs to them.
+// It fails with storage.ErrEmailAlreadyTaken if another account has the address
+func setVerifiedEmail(ctx context.Context, tx pgx.Tx, userId, email string) error {
+	now := time.Now()
+
+	sql, args, err := squirrel.Update("users").
+		SetMap(squirrel.Eq{"email": email, "email_verified_at": now, "updated_at": now}).
+		Where(squirrel.Eq{"id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return storage.ErrEmailAlreadyTaken
+		}
+
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// revokeSessions signs a user out everywhere, revoking the sessions and forgetting the trusted devices
+func revokeSessions(ctx context.Context, tx pgx.Tx, userId string) error {
+	sql, args, err := squirrel.Update("sessions").
+		SetMap(squirrel.Eq{"revoked_at": time.Now()}).
+		Where(squirrel.Eq{"user_id": userId, "revoked_at": nil}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
 
+	sql, args, err = squirrel.Delete("trusted_devices").
+		Where(squirrel.Eq{"user_id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+
+	return err
+}
+
+// setPassword replaces the password of a user, keeping the previous one in the history
+func setPassword(ctx context.Context, tx pgx.Tx, userId, password string, historySize int) error {
 	sql, args, err := squirrel.Select("password").
 		From("users").
-		Where(squirrel.Eq{"id": userId, "password": password}).
+		Where(squirrel.Eq{"id": userId}).
+		Suffix("FOR UPDATE").
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return err
 	}
 
-	var userPassword string
-	err = s.db.QueryRow(ctx, sql, args...).Scan(&userPassword)
-	if err != nil {
+	var previous string
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&previous); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return "", fmt.Errorf("%s: %w", op, storage.ErrWrongPassword)
+			return storage.ErrUserNotFound
 		}
-		return "", fmt.Errorf("%s: %w", op, err)
+
+		return err
 	}
 
-	return userPassword, nil
+	now := time.Now()
+
+	if historySize > 0 && previous != "" {
+		sql, args, err = squirrel.Insert("password_history").
+			Columns("user_id", "password_hash", "created_at").
+			Values(userId, previous, now).
+			PlaceholderFormat(squirrel.Dollar).
+			ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+
+	if err := prunePasswordHistory(ctx, tx, userId, historySize); err != nil {
+		return err
+	}
+
+	// Whoever sets a password of their own has no reason to change it anymore
+	sql, args, err = squirrel.Update("users").
+		SetMap(squirrel.Eq{"password": password, "must_change_password": false}).
+		SetMap(squirrel.Eq{"password_changed_at": now, "updated_at": now}).
+		Where(squirrel.Eq{"id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+
+	return err
 }
 
-func (s *Storage) UpdatePassword(ctx context.Context, userId, password string) error {
-	const op = "storage.Postgres.UpdatePassword"
+// RehashPassword replaces the hash of a password by one of a newer scheme, unless the password
+// changed since the old hash was read
+func (s *Storage) RehashPassword(ctx context.Context, userId, oldHash, newHash string) error {
+	const op = "storage.Postgres.RehashPassword"
 
 	sql, args, err := squirrel.Update("users").
-		SetMap(squirrel.Eq{"password": password}).
-		SetMap(squirrel.Eq{"updated_at": time.Now()}).
-		Where(squirrel.Eq{"id": userId}).
+		SetMap(squirrel.Eq{"password": newHash}).
+		Where(squirrel.Eq{"id": userId, "password": oldHash}).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
 	if err != nil {
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+func (s *Storage) GetProfile(ctx context.Context, userId string) (*models.Profile, error) {
+	const op = "storage.Postgres.GetProfile"
+
+	sql, args, err := squirrel.Select(
+		"user_id",
+		"display_name",
+		"avatar_url",
+		"locale",
+		"timezone",
+		"metadata",
+		"version",
+		"updated_at",
+	).
+		From("user_profiles").
+		Where(squirrel.Eq{"user_id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var profile models.Profile
+	var metadata []byte
+	var updatedAt time.Time
+
+	err = s.db.QueryRow(ctx, sql, args...).Scan(
+		&profile.UserID,
+		&profile.DisplayName,
+		&profile.AvatarURL,
+		&profile.Locale,
+		&profile.Timezone,
+		&metadata,
+		&profile.Version,
+		&updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrProfileNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := json.Unmarshal(metadata, &profile.Metadata); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	profile.UpdatedAt = &updatedAt
+
+	return &profile, nil
+}
+
+// SaveProfile writes the profile only if the stored one is still at expectedVersion,
+// zero meaning that no profile is stored yet. It returns the version after the write
+func (s *Storage) SaveProfile(ctx context.Context, profile *models.Profile, expectedVersion int64) (int64, error) {
+	const op = "storage.Postgres.SaveProfile"
+
+	metadata, err := json.Marshal(profile.Metadata)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var sql string
+	var args []interface{}
+	if expectedVersion == 0 {
+		sql, args, err = squirrel.Insert("user_profiles").
+			Columns("user_id", "display_name", "avatar_url", "locale", "timezone", "metadata", "version", "updated_at").
+			Values(
+				profile.UserID,
+				profile.DisplayName,
+				profile.AvatarURL,
+				profile.Locale,
+				profile.Timezone,
+				metadata,
+				1,
+				time.Now(),
+			).
+			Suffix("ON CONFLICT (user_id) DO NOTHING RETURNING version").
+			PlaceholderFormat(squirrel.Dollar).
+			ToSql()
+	} else {
+		sql, args, err = squirrel.Update("user_profiles").
+			SetMap(squirrel.Eq{
+				"display_name": profile.DisplayName,
+				"avatar_url":   profile.AvatarURL,
+				"locale":       profile.Locale,
+				"timezone":     profile.Timezone,
+				"metadata":     metadata,
+				"version":      squirrel.Expr("version + 1"),
+				"updated_at":   time.Now(),
+			}).
+			Where(squirrel.Eq{"user_id": profile.UserID, "version": expectedVersion}).
+			Suffix("RETURNING version").
+			PlaceholderFormat(squirrel.Dollar).
+			ToSql()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var version int64
+	if err := s.db.QueryRow(ctx, sql, args...).Scan(&version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+func (s *Storage) SaveSession(ctx context.Context, session *models.Session) error {
+	const op = "storage.Postgres.SaveSession"
+
+	sql, args, err := squirrel.Insert("sessions").
+		Columns("id", "user_id", "refresh_token_hash", "ip", "user_agent", "created_at", "expires_at").
+		Values(session.ID, session.UserID, session.RefreshTokenHash, session.IP, session.UserAgent, session.CreatedAt, session.ExpiresAt).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// IsSessionActive reports whether the session exists, has not expired and has not been revoked
+func (s *Storage) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	const op = "storage.Postgres.IsSessionActive"
+
+	sql, args, err := squirrel.Select("id").
+		From("sessions").
+		Where(squirrel.Eq{"id": sessionId, "revoked_at": nil}).
+		Where(squirrel.Gt{"expires_at": time.Now()}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var id string
+	err = s.db.QueryRow(ctx, sql, args...).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// RevokeUserSessions revokes every live session and with it every refresh token of a user
+func (s *Storage) RevokeUserSessions(ctx context.Context, userId string) (int64, error) {
+	const op = "storage.Postgres.RevokeUserSessions"
+
+	sql, args, err := squirrel.Update("sessions").
+		SetMap(squirrel.Eq{"revoked_at": time.Now()}).
+		Where(squirrel.Eq{"user_id": userId, "revoked_at": nil}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (s *Storage) ListUserSessions(ctx context.Context, userId string) ([]models.Session, error) {
+	const op = "storage.Postgres.ListUserSessions"
+
+	sql, args, err := squirrel.Select("id", "user_id", "ip", "user_agent", "created_at", "expires_at", "revoked_at").
+		From("sessions").
+		Where(squirrel.Eq{"user_id": userId}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		var revokedAt pgtype.Timestamp
+
+		if err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.IP,
+			&session.UserAgent,
+			&session.CreatedAt,
+			&session.ExpiresAt,
+			&revokedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if revokedAt.Valid {
+			session.RevokedAt = &revokedAt.Time
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
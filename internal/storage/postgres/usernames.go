@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"AuthService/internal/domain/models"
+	"AuthService/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// ChangeUsername renames the user and keeps the previous username in the history,
+// reserved until the given time
+func (s *Storage) ChangeUsername(ctx context.Context, userId, username string, reservedUntil time.Time) error {
+	const op = "storage.Postgres.ChangeUsername"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, err := squirrel.Select("username").
+		From("users").
+		Where(squirrel.Eq{"id": userId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var previous string
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&previous); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+
+	sql, args, err = squirrel.Insert("username_history").
+		Columns("id", "user_id", "username", "changed_at", "reserved_until").
+		Values(id, userId, previous, now, reservedUntil).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql, args, err = squirrel.Update("users").
+		SetMap(squirrel.Eq{"username": username, "updated_at": now}).
+		Where(squirrel.Eq{"id": userId}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListUsernameHistory returns the previous usernames of a user, the latest change first
+func (s *Storage) ListUsernameHistory(ctx context.Context, userId string) ([]models.UsernameChange, error) {
+	const op = "storage.Postgres.ListUsernameHistory"
+
+	sql, args, err := squirrel.Select("id", "user_id", "username", "changed_at", "reserved_until").
+		From("username_history").
+		Where(squirrel.Eq{"user_id": userId}).
+		OrderBy("changed_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	var changes []models.UsernameChange
+	for rows.Next() {
+		var change models.UsernameChange
+		if err := rows.Scan(&change.ID, &change.UserID, &change.Username, &change.ChangedAt, &change.ReservedUntil); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return changes, nil
+}
+
+// isUsernameReserved tells whether a previous owner still holds the username. The reservations
+// of ownerId, when given, don't count
+func (s *Storage) isUsernameReserved(ctx context.Context, username, ownerId string) (bool, error) {
+	query := squirrel.Select("1").
+		From("username_history").
+		Where(squirrel.Eq{"username": username}).
+		Where(squirrel.Gt{"reserved_until": time.Now()})
+	if ownerId != "" {
+		query = query.Where(squirrel.NotEq{"user_id": ownerId})
+	}
+
+	sql, args, err := query.
+		Limit(1).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	var found int
+	if err := s.db.QueryRow(ctx, sql, args...).Scan(&found); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
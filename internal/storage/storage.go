@@ -5,10 +5,18 @@ import (
 )
 
 var (
-	ErrUserNotFound      = errors.New("user not found")
-	ErrUserAlreadyExists = errors.New("user already exists")
-	ErrNoActiveSession   = errors.New("user already logged out")
-	ErrEmailAlreadyTaken = errors.New("email already taken")
-	ErrWrongEmail        = errors.New("wrong email")
-	ErrWrongPassword     = errors.New("wrong password")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserAlreadyExists  = errors.New("user already exists")
+	ErrNoActiveSession    = errors.New("user already logged out")
+	ErrEmailAlreadyTaken  = errors.New("email already taken")
+	ErrWrongEmail         = errors.New("wrong email")
+	ErrWrongPassword      = errors.New("wrong password")
+	ErrDeviceNotFound     = errors.New("device not found")
+	ErrTokenNotFound      = errors.New("token not found")
+	ErrExportNotFound     = errors.New("export not found")
+	ErrProfileNotFound    = errors.New("profile not found")
+	ErrVersionConflict    = errors.New("version conflict")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrPolicyExists       = errors.New("policy version already exists")
+	ErrLockoutNotFound    = errors.New("lockout not found")
 )
@@ -1,7 +1,8 @@
 package middlewares
 
+// CheckRegister checks the login and email, the password is checked by the password policy
 func CheckRegister(login, email, password string) bool {
-	if login == "" || email == "" || password == "" || CorrectEmailChecker(email) == false || len(login) < 3 || len(password) < 8 {
+	if login == "" || email == "" || password == "" || CorrectEmailChecker(email) == false || len(login) < 3 {
 		return false
 	}
 	return true
@@ -0,0 +1,11 @@
+package middlewares
+
+// CheckUsername rejects usernames that are too short or too long and ones that look like an email,
+// since logins tell the two apart by their shape
+func CheckUsername(username string) bool {
+	if len(username) < 3 || len(username) > 255 || CorrectEmailChecker(username) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE trusted_devices
+(
+    id               UUID PRIMARY KEY,
+    user_id          UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name             VARCHAR(255) NOT NULL DEFAULT '',
+    fingerprint_hash VARCHAR(64)  NOT NULL,
+    created_at       TIMESTAMP    NOT NULL DEFAULT NOW(),
+    last_used_at     TIMESTAMP    NOT NULL DEFAULT NOW(),
+    expires_at       TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX trusted_devices_user_id_idx ON trusted_devices (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS trusted_devices;
+-- +goose StatementEnd
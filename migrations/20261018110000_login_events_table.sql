@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE login_events
+(
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        UUID                  REFERENCES users (id) ON DELETE CASCADE,
+    ip             VARCHAR(45)  NOT NULL DEFAULT '',
+    network        VARCHAR(49)  NOT NULL DEFAULT '',
+    user_agent     VARCHAR(255) NOT NULL DEFAULT '',
+    device_trusted BOOLEAN      NOT NULL DEFAULT FALSE,
+    success        BOOLEAN      NOT NULL,
+    risk_score     INTEGER      NOT NULL DEFAULT 0,
+    risk_decision  VARCHAR(16)  NOT NULL DEFAULT '',
+    signals        JSONB        NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX login_events_user_id_created_at_idx ON login_events (user_id, created_at);
+
+-- Failed logins of names without an account, kept to lock them out like accounts
+CREATE INDEX login_events_unknown_created_at_idx ON login_events (created_at) WHERE user_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_events;
+-- +goose StatementEnd
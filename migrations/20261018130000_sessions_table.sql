@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE sessions
+(
+    id                 UUID PRIMARY KEY,
+    user_id            UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    refresh_token_hash VARCHAR(64)  NOT NULL UNIQUE,
+    ip                 VARCHAR(45)  NOT NULL DEFAULT '',
+    user_agent         VARCHAR(255) NOT NULL DEFAULT '',
+    created_at         TIMESTAMP    NOT NULL DEFAULT NOW(),
+    expires_at         TIMESTAMP    NOT NULL,
+    revoked_at         TIMESTAMP             DEFAULT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sessions;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE email_changes
+(
+    id                 UUID PRIMARY KEY,
+    user_id            UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    old_email          VARCHAR(255) NOT NULL,
+    new_email          VARCHAR(255) NOT NULL,
+    confirm_token_hash VARCHAR(64)  NOT NULL UNIQUE,
+    revert_token_hash  VARCHAR(64)  NOT NULL UNIQUE,
+    created_at         TIMESTAMP    NOT NULL DEFAULT NOW(),
+    expires_at         TIMESTAMP    NOT NULL,
+    revert_expires_at  TIMESTAMP    NOT NULL,
+    confirmed_at       TIMESTAMP             DEFAULT NULL,
+    reverted_at        TIMESTAMP             DEFAULT NULL
+);
+
+CREATE INDEX email_changes_user_id_idx ON email_changes (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS email_changes;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN deletion_scheduled_at TIMESTAMP DEFAULT NULL;
+
+-- No foreign keys, audit events have to survive the erasure of their user
+CREATE TABLE audit_events
+(
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    UUID                 DEFAULT NULL,
+    actor_id   UUID                 DEFAULT NULL,
+    action     VARCHAR(64) NOT NULL,
+    details    JSONB       NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_events_user_id_idx ON audit_events (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_events;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deletion_scheduled_at;
+-- +goose StatementEnd
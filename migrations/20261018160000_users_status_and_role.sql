@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN role              VARCHAR(16)  NOT NULL DEFAULT 'user',
+    ADD COLUMN status            VARCHAR(16)  NOT NULL DEFAULT 'active',
+    ADD COLUMN status_reason     VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN status_actor_id   UUID                  DEFAULT NULL,
+    ADD COLUMN status_expires_at TIMESTAMP             DEFAULT NULL,
+    ADD COLUMN status_changed_at TIMESTAMP             DEFAULT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN IF EXISTS status_changed_at,
+    DROP COLUMN IF EXISTS status_expires_at,
+    DROP COLUMN IF EXISTS status_actor_id,
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS role;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE data_exports
+(
+    id           UUID PRIMARY KEY,
+    user_id      UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status       VARCHAR(16)  NOT NULL DEFAULT 'pending',
+    format       VARCHAR(8)   NOT NULL DEFAULT 'json',
+    archive      BYTEA                 DEFAULT NULL,
+    error        VARCHAR(255) NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT NOW(),
+    claimed_at   TIMESTAMP             DEFAULT NULL,
+    completed_at TIMESTAMP             DEFAULT NULL,
+    expires_at   TIMESTAMP             DEFAULT NULL
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id);
+CREATE INDEX data_exports_status_idx ON data_exports (status, created_at);
+CREATE INDEX data_exports_running_idx ON data_exports (claimed_at) WHERE status = 'running';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS data_exports;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE user_profiles
+(
+    user_id      UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    display_name VARCHAR(64)  NOT NULL DEFAULT '',
+    avatar_url   VARCHAR(512) NOT NULL DEFAULT '',
+    locale       VARCHAR(35)  NOT NULL DEFAULT '',
+    timezone     VARCHAR(64)  NOT NULL DEFAULT '',
+    metadata     JSONB        NOT NULL DEFAULT '{}',
+    version      BIGINT       NOT NULL DEFAULT 1,
+    updated_at   TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_profiles;
+-- +goose StatementEnd
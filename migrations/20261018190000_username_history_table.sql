@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE username_history
+(
+    id             UUID PRIMARY KEY,
+    user_id        UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    username       VARCHAR(255) NOT NULL,
+    changed_at     TIMESTAMP    NOT NULL DEFAULT NOW(),
+    reserved_until TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX username_history_user_id_idx ON username_history (user_id, changed_at);
+CREATE INDEX username_history_username_idx ON username_history (username, reserved_until);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS username_history;
+-- +goose StatementEnd
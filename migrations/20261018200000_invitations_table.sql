@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE invitations
+(
+    id         UUID PRIMARY KEY,
+    code_hash  VARCHAR(64)  NOT NULL UNIQUE,
+    email      VARCHAR(255) NOT NULL DEFAULT '',
+    created_by UUID                  REFERENCES users (id) ON DELETE SET NULL,
+    max_uses   INTEGER      NOT NULL DEFAULT 1,
+    uses       INTEGER      NOT NULL DEFAULT 0,
+    created_at TIMESTAMP    NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP             DEFAULT NULL,
+    revoked_at TIMESTAMP             DEFAULT NULL
+);
+
+CREATE INDEX invitations_created_by_idx ON invitations (created_by);
+
+ALTER TABLE users
+    ADD COLUMN invited_by    UUID DEFAULT NULL,
+    ADD COLUMN invitation_id UUID DEFAULT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN IF EXISTS invitation_id,
+    DROP COLUMN IF EXISTS invited_by;
+
+DROP TABLE IF EXISTS invitations;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE policy_documents
+(
+    id           UUID PRIMARY KEY,
+    kind         VARCHAR(32)  NOT NULL,
+    version      VARCHAR(32)  NOT NULL,
+    url          VARCHAR(512) NOT NULL,
+    mandatory    BOOLEAN      NOT NULL DEFAULT TRUE,
+    published_at TIMESTAMP    NOT NULL DEFAULT NOW(),
+    UNIQUE (kind, version)
+);
+
+CREATE INDEX policy_documents_kind_idx ON policy_documents (kind, published_at);
+
+CREATE TABLE policy_acceptances
+(
+    user_id     UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    document_id UUID         NOT NULL REFERENCES policy_documents (id),
+    accepted_at TIMESTAMP    NOT NULL DEFAULT NOW(),
+    ip          VARCHAR(45)  NOT NULL DEFAULT '',
+    user_agent  VARCHAR(512) NOT NULL DEFAULT '',
+    PRIMARY KEY (user_id, document_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS policy_acceptances;
+DROP TABLE IF EXISTS policy_documents;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN password_changed_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- Without a record of it, the last change of an existing password is at most its last update
+UPDATE users
+SET password_changed_at = COALESCE(updated_at, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN IF EXISTS password_changed_at;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE login_lockouts
+(
+    subject         VARCHAR(80) PRIMARY KEY,
+    user_id         UUID                 REFERENCES users (id) ON DELETE CASCADE,
+    failed_attempts INTEGER     NOT NULL DEFAULT 0,
+    last_failed_at  TIMESTAMP   NOT NULL,
+    locked_until    TIMESTAMP            DEFAULT NULL,
+    lockouts        INTEGER     NOT NULL DEFAULT 0
+);
+
+CREATE INDEX login_lockouts_unknown_last_failed_at_idx ON login_lockouts (last_failed_at) WHERE user_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_lockouts;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE login_failures
+(
+    id            BIGSERIAL PRIMARY KEY,
+    ip            VARCHAR(45) NOT NULL DEFAULT '',
+    network       VARCHAR(49) NOT NULL DEFAULT '',
+    username_hash VARCHAR(64) NOT NULL,
+    created_at    TIMESTAMP   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX login_failures_ip_created_at_idx ON login_failures (ip, created_at);
+CREATE INDEX login_failures_network_created_at_idx ON login_failures (network, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_failures;
+-- +goose StatementEnd
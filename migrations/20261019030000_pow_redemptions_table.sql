@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE pow_redemptions
+(
+    nonce      VARCHAR(32) PRIMARY KEY,
+    expires_at TIMESTAMP   NOT NULL
+);
+
+CREATE INDEX pow_redemptions_expires_at_idx ON pow_redemptions (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pow_redemptions;
+-- +goose StatementEnd